@@ -0,0 +1,187 @@
+package main
+
+import "math"
+
+// Arc is an svg elliptical arc in endpoint form
+type Arc struct {
+	p0, p1 Point
+	rx, ry float64
+	// rotation of the x axis in degrees
+	rotation     float64
+	large, sweep bool
+}
+
+// center converts the arc to center form following the svg implementation
+// notes, radii too small to span the endpoints are scaled up to fit. ok is
+// false when the arc is really a straight line.
+func (a Arc) center() (c Point, rx, ry, phi, theta, delta float64, ok bool) {
+	rx, ry = math.Abs(a.rx), math.Abs(a.ry)
+	if rx == 0 || ry == 0 || a.p0.Equals(a.p1) {
+		return
+	}
+
+	phi = a.rotation * math.Pi / 180
+	sin, cos := math.Sincos(phi)
+
+	dx, dy := (a.p0.X-a.p1.X)/2, (a.p0.Y-a.p1.Y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	// radius correction
+	if lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry); lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx, ry = rx*s, ry*s
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if a.large == a.sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+
+	c = Point{
+		X: cos*cx1 - sin*cy1 + (a.p0.X+a.p1.X)/2,
+		Y: sin*cx1 + cos*cy1 + (a.p0.Y+a.p1.Y)/2,
+	}
+
+	theta = math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	delta = math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx) - theta
+	if a.sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !a.sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	ok = true
+	return
+}
+
+// Linearize flattens the arc by stepping its angle by res of the sweep, each
+// step is split further when maxLength is positive until no segment is longer
+// than it
+func (a Arc) Linearize(res, maxLength float64) (ret []Point) {
+	c, rx, ry, phi, theta, delta, ok := a.center()
+	if !ok {
+		return []Point{a.p1}
+	}
+	sin, cos := math.Sincos(phi)
+	at := func(e float64) Point {
+		st, ct := math.Sincos(theta + e*delta)
+		return Point{
+			X: c.X + rx*cos*ct - ry*sin*st,
+			Y: c.Y + rx*sin*ct + ry*cos*st,
+		}
+	}
+	// a piece is halved until its chord is within maxLength
+	var divide func(e0, e1 float64, depth int)
+	divide = func(e0, e1 float64, depth int) {
+		p0, p1 := at(e0), at(e1)
+		if math.Hypot(p1.X-p0.X, p1.Y-p0.Y) <= maxLength || depth >= maxBezierDepth {
+			return
+		}
+		mid := (e0 + e1) / 2
+		divide(e0, mid, depth+1)
+		ret = append(ret, at(mid))
+		divide(mid, e1, depth+1)
+	}
+
+	prev := 0.
+	for e := res; e < 1.0; e += res {
+		if maxLength > 0 {
+			divide(prev, e, 0)
+		}
+		ret = append(ret, at(e))
+		prev = e
+	}
+	if maxLength > 0 {
+		divide(prev, 1, 0)
+	}
+	// land exactly on the endpoint
+	ret = append(ret, a.p1)
+	return
+}
+
+// Cubics approximates the arc with a cubic bezier for every quarter turn or
+// less of its sweep, each meeting the ellipse at its ends and middle. A
+// straight arc is a single cubic along the line.
+func (a Arc) Cubics() []Bezier {
+	c, rx, ry, phi, theta, delta, ok := a.center()
+	if !ok {
+		return []Bezier{{p0: a.p0, c0: a.p0, c1: a.p1, p1: a.p1}}
+	}
+	// the unit circle is drawn and mapped onto the ellipse
+	ellipse := Translate(c.X, c.Y).Multiply(Rotate(phi)).Multiply(Scale(rx, ry))
+	n := int(math.Ceil(math.Abs(delta)/(math.Pi/2) - 1e-9))
+	if n < 1 {
+		n = 1
+	}
+	step := delta / float64(n)
+	// distance of the control points along the tangents
+	k := 4. / 3 * math.Tan(step/4)
+
+	ret := make([]Bezier, n)
+	for i := range ret {
+		t0, t1 := theta+float64(i)*step, theta+float64(i+1)*step
+		s0, c0 := math.Sincos(t0)
+		s1, c1 := math.Sincos(t1)
+		ret[i] = Bezier{
+			p0: ellipse.Apply(Point{X: c0, Y: s0}),
+			c0: ellipse.Apply(Point{X: c0 - k*s0, Y: s0 + k*c0}),
+			c1: ellipse.Apply(Point{X: c1 + k*s1, Y: s1 - k*c1}),
+			p1: ellipse.Apply(Point{X: c1, Y: s1}),
+		}
+	}
+	// land exactly on the endpoints
+	ret[0].p0, ret[n-1].p1 = a.p0, a.p1
+	return ret
+}
+
+// LinearizeCubics flattens the Cubics of the arc, each stepped so the whole
+// arc has about as many points as Linearize gives it
+func (a Arc) LinearizeCubics(res, maxLength float64) (ret []Point) {
+	cubics := a.Cubics()
+	step := math.Min(1, res*float64(len(cubics)))
+	for _, b := range cubics {
+		// the start of each is the end of the one before, or the start of
+		// the arc which isn't part of its points
+		ret = append(ret, b.Linearize(step, maxLength)[1:]...)
+	}
+	return
+}
+
+func (a Arc) linearize(f flattening) []Point {
+	if f.arcsAsCubics {
+		return a.LinearizeCubics(f.res, f.maxLength)
+	}
+	return a.Linearize(f.res, f.maxLength)
+}
+
+type SVGDAbsoluteArcPart struct {
+	rx, ry, rotation float64
+	large, sweep     bool
+	Point
+}
+
+func (p SVGDAbsoluteArcPart) Linearize(start Point, res float64) []Point {
+	return p.linearize(start, flattening{res: res})
+}
+
+func (p SVGDAbsoluteArcPart) linearize(start Point, f flattening) []Point {
+	return Arc{p0: start, p1: p.Point, rx: p.rx, ry: p.ry, rotation: p.rotation, large: p.large, sweep: p.sweep}.linearize(f)
+}
+
+type SVGDRelativeArcPart struct {
+	rx, ry, rotation float64
+	large, sweep     bool
+	Point
+}
+
+func (p SVGDRelativeArcPart) Linearize(start Point, res float64) []Point {
+	return p.linearize(start, flattening{res: res})
+}
+
+func (p SVGDRelativeArcPart) linearize(start Point, f flattening) []Point {
+	return Arc{p0: start, p1: start.Add(p.Point), rx: p.rx, ry: p.ry, rotation: p.rotation, large: p.large, sweep: p.sweep}.linearize(f)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestArcUndersizedRadii(t *testing.T) {
+	// radii of 1 can't span 10 units, they scale up to a half circle of 5
+	a := Arc{p0: Point{X: 0, Y: 0}, p1: Point{X: 10, Y: 0}, rx: 1, ry: 1, rotation: 30}
+	points := a.Linearize(0.05, 0)
+	if len(points) < 3 {
+		t.Fatalf("got %v, want a curve", points)
+	}
+	// the start is the current point so the arc begins one step past it
+	if first := points[0]; math.Hypot(first.X-a.p0.X, first.Y-a.p0.Y) > 1 {
+		t.Errorf("arc starts at %v, far from %v", first, a.p0)
+	}
+	if last := points[len(points)-1]; !last.Equals(a.p1) {
+		t.Errorf("arc ends at %v, want %v", last, a.p1)
+	}
+	for _, p := range points {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) {
+			t.Fatalf("arc has NaN points %v", points)
+		}
+		if r := math.Hypot(p.X-5, p.Y); math.Abs(r-5) > 1e-9 {
+			t.Errorf("%v is %g from the center, want 5", p, r)
+		}
+	}
+}
+
+func TestArcCubicsAccuracy(t *testing.T) {
+	const r = 10.
+	a := Arc{p0: Point{X: r, Y: 0}, p1: Point{X: -r, Y: 0}, rx: r, ry: r, sweep: true}
+	// furthest the polyline strays from the circle, at its points and the
+	// middles of its segments
+	stray := func(points []Point) (worst float64) {
+		points = append([]Point{a.p0}, points...)
+		for i := 1; i < len(points); i++ {
+			p, q := points[i-1], points[i]
+			for _, s := range []Point{q, {X: (p.X + q.X) / 2, Y: (p.Y + q.Y) / 2}} {
+				worst = math.Max(worst, math.Abs(math.Hypot(s.X, s.Y)-r))
+			}
+		}
+		return
+	}
+	// a quarter circle cubic is off the circle by at most this much
+	bound := 2.8e-4 * r
+	for _, res := range []float64{0.025, 0.01} {
+		lines, cubics := a.Linearize(res, 0), a.LinearizeCubics(res, 0)
+		if len(lines) != len(cubics) {
+			t.Fatalf("res %g: got %d points as cubics and %d as lines, want the same", res, len(cubics), len(lines))
+		}
+		if l, c := stray(lines), stray(cubics); c > l+bound {
+			t.Errorf("res %g: cubics stray %g from the arc, want within %g of the %g of lines", res, c, bound, l)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BatchResult is the outcome of converting one file of a directory
+type BatchResult struct {
+	Input  string
+	Output string
+	Err    error
+}
+
+// formatExtension is the file extension written for an output format
+func formatExtension(format string) string {
+	switch format {
+	case "stlb":
+		return ".stl"
+	case "webgl", "webgl64":
+		return ".json"
+	}
+	return "." + format
+}
+
+// ConvertDirectory converts every .svg and .svgz file directly inside dir to
+// a file of the same name in outdir, with the extension of format. Up to
+// workers files are converted at once. The results are in file name order
+// and a file failing doesn't stop the others.
+func ConvertDirectory(dir, outdir, format string, workers int, opts Options) ([]BatchResult, error) {
+	if _, err := NewMeshWriter(format, nil, opts); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		return nil, err
+	}
+
+	var results []BatchResult
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".svg" && ext != ".svgz") {
+			continue
+		}
+		results = append(results, BatchResult{
+			Input:  filepath.Join(dir, e.Name()),
+			Output: filepath.Join(outdir, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))+formatExtension(format)),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Input < results[j].Input })
+
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan *BatchResult)
+	var wg sync.WaitGroup
+	// metrics are kept per file and added up at the end
+	var mu sync.Mutex
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				fileOpts := opts
+				if opts.Metrics != nil {
+					fileOpts.Metrics = &Metrics{}
+				}
+				r.Err = convertFile(r.Input, r.Output, format, fileOpts)
+				if opts.Metrics != nil {
+					mu.Lock()
+					opts.Metrics.add(fileOpts.Metrics)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range results {
+		jobs <- &results[i]
+	}
+	close(jobs)
+	wg.Wait()
+	return results, nil
+}
+
+// convertFile converts the svg at input to a new file at output, which is
+// removed again if the conversion fails
+func convertFile(input, output, format string, opts Options) (err error) {
+	in, err := OpenInput(input, opts)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	elements, err := ParseDocument(in, opts)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		// don't leave a partial file behind
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+	writer, err := NewMeshWriter(format, out, opts)
+	if err != nil {
+		return err
+	}
+	if err := WalkPolygons(elements, opts, writer.WritePolygon); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// reportBatch prints the outcome of every file and how many failed
+func reportBatch(results []BatchResult) (failed int) {
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "failed %s: %v\n", r.Input, r.Err)
+		} else {
+			fmt.Fprintf(os.Stderr, "ok %s -> %s\n", r.Input, r.Output)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d of %d files converted\n", len(results)-failed, len(results))
+	return
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertDirectory(t *testing.T) {
+	dir := t.TempDir()
+	outdir := filepath.Join(dir, "out")
+	for name, content := range map[string]string{
+		"a.svg":     `<svg><rect width="10" height="10"/></svg>`,
+		"b.svg":     `<svg><rect width="10" height="10"/><rect x="20" width="10" height="10"/></svg>`,
+		"notes.txt": "not an svg",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := ConvertDirectory(dir, outdir, "json", 2, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got results %v, want one for each svg", results)
+	}
+	for i, want := range []int{1, 2} {
+		r := results[i]
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Input, r.Err)
+			continue
+		}
+		b, err := os.ReadFile(r.Output)
+		if err != nil {
+			t.Errorf("%s: %v", r.Input, err)
+			continue
+		}
+		var polys []json.RawMessage
+		if err := json.Unmarshal(b, &polys); err != nil {
+			t.Errorf("%s: %v", r.Output, err)
+		} else if len(polys) != want {
+			t.Errorf("%s has %d polygons, want %d", r.Output, len(polys), want)
+		}
+	}
+	if filepath.Base(results[0].Output) != "a.json" {
+		t.Errorf("wrote %s, want a.json", results[0].Output)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+type BoundingBox struct {
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+}
+
+// Bounds returns the smallest axis aligned box around the ring, an empty ring
+// has an empty box at the origin
+func (r Ring) Bounds() BoundingBox {
+	if len(r) == 0 {
+		return BoundingBox{}
+	}
+	b := BoundingBox{
+		Min: Point{X: math.Inf(1), Y: math.Inf(1)},
+		Max: Point{X: math.Inf(-1), Y: math.Inf(-1)},
+	}
+	for _, p := range r {
+		b.Min.X, b.Min.Y = math.Min(b.Min.X, p.X), math.Min(b.Min.Y, p.Y)
+		b.Max.X, b.Max.Y = math.Max(b.Max.X, p.X), math.Max(b.Max.Y, p.Y)
+	}
+	return b
+}
+
+// Bounds of a polygon are those of its exterior, which the holes are inside
+func (p Polygon) Bounds() BoundingBox {
+	return Ring(p.Exterior).Bounds()
+}
+
+// OrientedBoundingBox returns the corners of the smallest area rectangle
+// around ring counter-clockwise, and the direction in radians of the edge from
+// the first corner to the second which is always in [0, pi/2). One side of
+// the rectangle lies along an edge of the convex hull, each is tried with
+// rotating calipers. A ring that is a line gives a rectangle of no height, a
+// single point four equal corners.
+func OrientedBoundingBox(ring []Point) (corners [4]Point, angle float64) {
+	hull := ConvexHull(ring)
+	n := len(hull)
+	switch n {
+	case 0:
+		return
+	case 1:
+		return [4]Point{hull[0], hull[0], hull[0], hull[0]}, 0
+	}
+
+	dot := func(p, d Point) float64 { return p.X*d.X + p.Y*d.Y }
+	best := math.Inf(1)
+	// furthest along the edge, away from it and back along it, the first edge
+	// starts each search where the previous one ended and later edges only
+	// ever move them forward
+	right, top, left := 1, 1, 1
+	for i := range hull {
+		base := hull[i]
+		e := Point{X: hull[(i+1)%n].X - base.X, Y: hull[(i+1)%n].Y - base.Y}
+		length := math.Hypot(e.X, e.Y)
+		u := Point{X: e.X / length, Y: e.Y / length}
+		// the hull is counter-clockwise so it is to the left of every edge
+		v := Point{X: -u.Y, Y: u.X}
+		along := func(k int) float64 { return dot(Point{X: hull[k%n].X - base.X, Y: hull[k%n].Y - base.Y}, u) }
+		away := func(k int) float64 { return dot(Point{X: hull[k%n].X - base.X, Y: hull[k%n].Y - base.Y}, v) }
+
+		for along(right+1) > along(right) {
+			right = (right + 1) % n
+		}
+		if i == 0 {
+			top = right
+		}
+		for away(top+1) > away(top) {
+			top = (top + 1) % n
+		}
+		if i == 0 {
+			left = top
+		}
+		for along(left+1) < along(left) {
+			left = (left + 1) % n
+		}
+
+		min, max, height := along(left), along(right), away(top)
+		if area := (max - min) * height; area < best {
+			best = area
+			at := func(a, b float64) Point {
+				return Point{X: base.X + a*u.X + b*v.X, Y: base.Y + a*u.Y + b*v.Y}
+			}
+			corners = [4]Point{at(min, 0), at(max, 0), at(max, height), at(min, height)}
+			angle = math.Atan2(u.Y, u.X)
+		}
+	}
+
+	// each corner turns a quarter, start from the one whose edge points
+	// into [0, pi/2)
+	k := int(math.Floor(angle / (math.Pi / 2)))
+	angle -= float64(k) * math.Pi / 2
+	start := ((-k)%4 + 4) % 4
+	var ret [4]Point
+	for i := range ret {
+		ret[i] = corners[(start+i)%4]
+	}
+	return ret, angle
+}
+
+// ShapeBounds is what the bounding box output writes for each shape
+type ShapeBounds struct {
+	ID   string      `json:"id,omitempty"`
+	Fill Color       `json:"fill"`
+	BBox BoundingBox `json:"bbox"`
+}
+
+// ExtractBounds converts the shapes under el to their bounding boxes only,
+// skipping triangulation
+func ExtractBounds(el *svgparser.Element, opts Options) (ret []ShapeBounds, err error) {
+	opts.Triangulate = false
+	err = WalkPolygons(el, opts, func(p Polygon) error {
+		ret = append(ret, ShapeBounds{ID: p.ID, Fill: p.Fill, BBox: p.Bounds()})
+		return nil
+	})
+	return
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExtractBounds(t *testing.T) {
+	opts := DefaultOptions()
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<path id="blob" d="M10 20 C40 0 60 40 30 50 Z" fill="#ff0000"/>
+<rect id="box" x="5" y="6" width="7" height="8" transform="translate(100 0)"/>
+</svg>`), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	bounds, err := ExtractBounds(doc, opts)
+	if err != nil {
+		t.Fatalf("extracting bounds: %v", err)
+	}
+	if len(bounds) != 2 {
+		t.Fatalf("got %v, want two shapes", bounds)
+	}
+	for _, b := range bounds {
+		switch b.ID {
+		case "box":
+			want := BoundingBox{Min: Point{X: 105, Y: 6}, Max: Point{X: 112, Y: 14}}
+			if b.BBox != want {
+				t.Errorf("box spans %v, want %v", b.BBox, want)
+			}
+		case "blob":
+			if b.Fill.Hex() != "#ff0000" || b.BBox.Min.X != 10 || b.BBox.Max.Y != 50 {
+				t.Errorf("got blob %v, want red from x 10 to y 50", b)
+			}
+		default:
+			t.Errorf("unexpected shape %v", b)
+		}
+	}
+}
+
+func TestOrientedBoundingBox(t *testing.T) {
+	theta := math.Pi / 6
+	rot := Rotate(theta)
+	var ring []Point
+	for _, p := range []Point{{X: 0, Y: 0}, {X: 8, Y: 0}, {X: 8, Y: 3}, {X: 0, Y: 3}, {X: 4, Y: 1}} {
+		p = rot.Apply(p)
+		ring = append(ring, Point{X: p.X + 5, Y: p.Y + 5})
+	}
+	corners, angle := OrientedBoundingBox(ring)
+	if math.Abs(angle-theta) > 1e-9 {
+		t.Errorf("got angle %g, want %g", angle, theta)
+	}
+	side := func(i int) float64 {
+		a, b := corners[i], corners[(i+1)%4]
+		return math.Hypot(b.X-a.X, b.Y-a.Y)
+	}
+	if w, h := side(0), side(1); math.Abs(w*h-24) > 1e-9 || math.Abs(math.Max(w, h)-8) > 1e-9 {
+		t.Errorf("got a %g by %g box, want 8 by 3", w, h)
+	}
+	if a := Ring(corners[:]).Area(); a <= 0 {
+		t.Errorf("corners wind with area %g, want counter-clockwise", a)
+	}
+
+	// a line has a box of no height along it
+	corners, angle = OrientedBoundingBox([]Point{{X: 0, Y: 0}, {X: 3, Y: 3}, {X: 1, Y: 1}})
+	if math.Abs(angle-math.Pi/4) > 1e-9 || math.Abs(Ring(corners[:]).Area()) > 1e-9 {
+		t.Errorf("line gave corners %v at %g, want a flat box at pi/4", corners, angle)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The binary format is a header of binaryMagic and binaryVersion followed by
+// a record for each polygon and a zero byte. Every number is little endian,
+// coordinates and colors are float64 so polygons read back exactly as they
+// were written. A record is a one byte, then:
+//
+//	id            uint32 length, bytes
+//	label         uint32 length, bytes
+//	fill          r, g, b, a float64
+//	exterior      ring
+//	holes         uint32 count, ring each
+//	triangles     uint32 count, three uint32 each
+//	clockwise     uint8 exterior, uint32 count, uint8 each hole
+//	steiner       ring
+//	colors        uint32 count, uint32 count and r, g, b, a float64 each
+//
+// where a ring is a uint32 count followed by x, y float64 for each point.
+// Version 1 records end after the steiner ring.
+const (
+	binaryMagic   = "ITS5"
+	binaryVersion = 2
+)
+
+// BinaryWriter writes polygons in the binary format read by ReadBinary
+type BinaryWriter struct {
+	w       io.Writer
+	started bool
+}
+
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	return &BinaryWriter{w: w}
+}
+
+func (b *BinaryWriter) header() error {
+	if b.started {
+		return nil
+	}
+	b.started = true
+	if _, err := io.WriteString(b.w, binaryMagic); err != nil {
+		return err
+	}
+	return binary.Write(b.w, binary.LittleEndian, uint32(binaryVersion))
+}
+
+func (b *BinaryWriter) WritePolygon(p Polygon) error {
+	if err := b.header(); err != nil {
+		return err
+	}
+	// writing to a bytes.Buffer can't fail
+	var buf bytes.Buffer
+	put := func(data interface{}) { binary.Write(&buf, binary.LittleEndian, data) }
+	ring := func(r []Point) {
+		put(uint32(len(r)))
+		for _, v := range r {
+			put([2]float64{v.X, v.Y})
+		}
+	}
+	flag := func(f bool) {
+		if f {
+			put(uint8(1))
+		} else {
+			put(uint8(0))
+		}
+	}
+
+	put(uint8(1))
+	for _, str := range []string{p.ID, p.Label} {
+		put(uint32(len(str)))
+		buf.WriteString(str)
+	}
+	put([4]float64{p.Fill.R, p.Fill.G, p.Fill.B, p.Fill.A})
+	ring(p.Exterior)
+	put(uint32(len(p.Holes)))
+	for _, h := range p.Holes {
+		ring(h)
+	}
+	put(uint32(len(p.Triangles)))
+	for _, t := range p.Triangles {
+		put([3]uint32{uint32(t[0]), uint32(t[1]), uint32(t[2])})
+	}
+	flag(p.ExteriorWasClockwise)
+	put(uint32(len(p.HolesWereClockwise)))
+	for _, c := range p.HolesWereClockwise {
+		flag(c)
+	}
+	ring(p.Steiner)
+	put(uint32(len(p.PerVertexColors)))
+	for _, cs := range p.PerVertexColors {
+		put(uint32(len(cs)))
+		for _, c := range cs {
+			put([4]float64{c.R, c.G, c.B, c.A})
+		}
+	}
+
+	_, err := b.w.Write(buf.Bytes())
+	return err
+}
+
+func (b *BinaryWriter) Close() error {
+	if err := b.header(); err != nil {
+		return err
+	}
+	_, err := b.w.Write([]byte{0})
+	return err
+}
+
+// WriteBinary writes polys in the binary format, see ReadBinary
+func WriteBinary(w io.Writer, polys []Polygon) error {
+	b := NewBinaryWriter(w)
+	for _, p := range polys {
+		if err := b.WritePolygon(p); err != nil {
+			return err
+		}
+	}
+	return b.Close()
+}
+
+// ReadBinary reads back the polygons written by WriteBinary or a
+// BinaryWriter
+func ReadBinary(r io.Reader) (polys []Polygon, err error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(binaryMagic))
+	if _, err = io.ReadFull(br, magic); err != nil {
+		return nil, err
+	} else if string(magic) != binaryMagic {
+		return nil, errors.New("not an itsfive binary file")
+	}
+
+	// the first error sticks and every later read returns zero values
+	get := func(data interface{}) {
+		if err == nil {
+			err = binary.Read(br, binary.LittleEndian, data)
+		}
+	}
+	count := func() (n uint32) {
+		get(&n)
+		return
+	}
+	ring := func() (ret []Point) {
+		// grown as points are read so a corrupt count can't allocate much
+		for n := count(); n > 0 && err == nil; n-- {
+			var v [2]float64
+			get(&v)
+			ret = append(ret, Point{X: v[0], Y: v[1]})
+		}
+		return
+	}
+	flag := func() bool {
+		var f uint8
+		get(&f)
+		return f != 0
+	}
+	str := func() string {
+		var sb strings.Builder
+		if n := count(); err == nil {
+			_, err = io.CopyN(&sb, br, int64(n))
+		}
+		return sb.String()
+	}
+
+	version := count()
+	if err == nil && (version < 1 || version > binaryVersion) {
+		return nil, fmt.Errorf("unsupported binary version %d", version)
+	}
+	for err == nil {
+		if !flag() {
+			break
+		}
+		var p Polygon
+		p.ID, p.Label = str(), str()
+		var fill [4]float64
+		get(&fill)
+		p.Fill = Color{R: fill[0], G: fill[1], B: fill[2], A: fill[3]}
+		p.Exterior = ring()
+		for n := count(); n > 0 && err == nil; n-- {
+			p.Holes = append(p.Holes, ring())
+		}
+		for n := count(); n > 0 && err == nil; n-- {
+			var t [3]uint32
+			get(&t)
+			p.Triangles = append(p.Triangles, Triangle{int(t[0]), int(t[1]), int(t[2])})
+		}
+		p.ExteriorWasClockwise = flag()
+		for n := count(); n > 0 && err == nil; n-- {
+			p.HolesWereClockwise = append(p.HolesWereClockwise, flag())
+		}
+		p.Steiner = ring()
+		if version >= 2 {
+			for n := count(); n > 0 && err == nil; n-- {
+				var cs []Color
+				for m := count(); m > 0 && err == nil; m-- {
+					var c [4]float64
+					get(&c)
+					cs = append(cs, Color{R: c[0], G: c[1], B: c[2], A: c[3]})
+				}
+				p.PerVertexColors = append(p.PerVertexColors, cs)
+			}
+		}
+		if err == nil {
+			polys = append(polys, p)
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	polys := convert(t, `<svg>
+<path id="donut" d="M0 0 L10 0 L10 10 L0 10 Z M4 4 L4 6 L6 6 L6 4 Z" fill="#ff000080"><title>Ring</title></path>
+<rect id="box" x="20" width="5" height="5" fill="#00ff00"/>
+</svg>`, DefaultOptions())
+	polys[0].PerVertexColors = [][]Color{{{R: 1, A: 1}, {G: 1, A: 0.5}}}
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, polys); err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	got, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if !reflect.DeepEqual(got, polys) {
+		t.Errorf("read back %+v, want %+v", got, polys)
+	}
+}
+
+// benchmarkPolygons converts a grid of curved shapes
+func benchmarkPolygons(b *testing.B) []Polygon {
+	var svg strings.Builder
+	svg.WriteString("<svg>")
+	for i := 0; i < 200; i++ {
+		x, y := float64(i%20)*30, float64(i/20)*30
+		fmt.Fprintf(&svg, `<path d="M%g %g C%g %g %g %g %g %g Z" fill="#336699"/>`, x, y, x+30, y, x+30, y+20, x, y+20)
+	}
+	svg.WriteString("</svg>")
+	doc, err := ParseDocument(strings.NewReader(svg.String()), DefaultOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+	polys, err := ExtractPolygons(doc, DefaultOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return polys
+}
+
+func BenchmarkReadBinary(b *testing.B) {
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, benchmarkPolygons(b)); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(buf.Len()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadBinary(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadJSON(b *testing.B) {
+	data, err := json.Marshal(benchmarkPolygons(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var polys []Polygon
+		if err := json.Unmarshal(data, &polys); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
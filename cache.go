@@ -0,0 +1,127 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// PathCache is an LRU of path geometry keyed on the d attribute and every
+// option that changes the result, so entries made under other options are
+// never returned. Fills are resolved per element and aren't cached. The
+// warnings and timings of making an entry are kept with it and reported
+// again on every hit. A nil cache caches nothing. It is safe for concurrent
+// use.
+type PathCache struct {
+	// lookups that found or missed an entry
+	Hits, Misses int
+
+	size    int
+	mu      sync.Mutex
+	order   *list.List
+	entries map[pathCacheKey]*list.Element
+}
+
+type pathCacheKey struct {
+	d           string
+	fillRule    FillRule
+	resolution  float64
+	maxSegment  float64
+	arcCubics   bool
+	triangulate bool
+	recenter    bool
+	lenient     bool
+	quality     float64
+	transform   Matrix
+	precision   int
+	snap        float64
+}
+
+type pathCacheEntry struct {
+	key    pathCacheKey
+	result pathResult
+}
+
+// pathResult is what converting the geometry of a path produced
+type pathResult struct {
+	polys    []Polygon
+	warnings []string
+	metrics  Metrics
+}
+
+// clone copies r so the copy's polygons may be changed
+func (r pathResult) clone() pathResult {
+	polys := make([]Polygon, len(r.polys))
+	for i, p := range r.polys {
+		polys[i] = p.Clone()
+	}
+	r.polys = polys
+	return r
+}
+
+func NewPathCache(size int) *PathCache {
+	return &PathCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[pathCacheKey]*list.Element),
+	}
+}
+
+func newPathCacheKey(el *svgparser.Element, opts Options) pathCacheKey {
+	// an invalid rule fails the conversion so it is never cached
+	rule, _ := fillRuleOf(el, opts)
+	return pathCacheKey{
+		d:           el.Attributes["d"],
+		fillRule:    rule,
+		resolution:  opts.Resolution,
+		maxSegment:  opts.MaxSegmentLength,
+		arcCubics:   opts.ArcsAsCubics,
+		triangulate: opts.Triangulate,
+		recenter:    opts.RecenterForTriangulation,
+		lenient:     opts.Lenient,
+		quality:     opts.MeshQuality,
+		transform:   opts.currentTransform(),
+		precision:   opts.Precision,
+		snap:        opts.SnapTolerance,
+	}
+}
+
+// get returns a copy of the cached result so callers are free to modify its
+// polygons
+func (c *PathCache) get(key pathCacheKey) (pathResult, bool) {
+	if c == nil {
+		return pathResult{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.Misses++
+		return pathResult{}, false
+	}
+	c.Hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*pathCacheEntry).result.clone(), true
+}
+
+func (c *PathCache) add(key pathCacheKey, r pathResult) {
+	if c == nil || c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*pathCacheEntry).result = r.clone()
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&pathCacheEntry{key: key, result: r.clone()})
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*pathCacheEntry).key)
+	}
+}
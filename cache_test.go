@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestPathCacheHit(t *testing.T) {
+	svg := `<svg><path d="M0 0 C10 0 10 10 0 10 Z" fill="#ff0000"/><path d="M0 0 C10 0 10 10 0 10 Z" fill="#0000ff"/></svg>`
+	opts := DefaultOptions()
+	opts.PathCache = NewPathCache(16)
+	polys := convert(t, svg, opts)
+	if opts.PathCache.Misses != 1 || opts.PathCache.Hits != 1 {
+		t.Fatalf("got %d misses and %d hits, want the second path to hit", opts.PathCache.Misses, opts.PathCache.Hits)
+	}
+	// fills aren't cached
+	if len(polys) != 2 || polys[0].Fill == polys[1].Fill {
+		t.Errorf("got %v, want the same shape in two fills", polys)
+	}
+
+	// callers get copies they may change
+	polys[0].Exterior[0] = Point{X: -100, Y: -100}
+	again := convert(t, svg, opts)
+	for _, p := range again {
+		if p.Exterior[0] == (Point{X: -100, Y: -100}) {
+			t.Error("changing a converted polygon changed the cache")
+		}
+	}
+
+	opts.Resolution /= 2
+	convert(t, svg, opts)
+	if opts.PathCache.Misses != 2 {
+		t.Errorf("got %d misses, a new resolution should miss the cache", opts.PathCache.Misses)
+	}
+}
+
+func TestPathCacheKeyRecenter(t *testing.T) {
+	svg := `<svg><path d="M0 0 L10 0 L10 10 L0 10 Z" fill="#000000"/></svg>`
+	opts := DefaultOptions()
+	opts.PathCache = NewPathCache(16)
+	convert(t, svg, opts)
+	convert(t, svg, opts)
+	if opts.PathCache.Hits != 1 {
+		t.Fatalf("got %d hits converting the same path twice, want 1", opts.PathCache.Hits)
+	}
+
+	opts.RecenterForTriangulation = true
+	convert(t, svg, opts)
+	if opts.PathCache.Hits != 1 || opts.PathCache.Misses != 2 {
+		t.Errorf("got %d hits and %d misses, recentering should miss the cache", opts.PathCache.Hits, opts.PathCache.Misses)
+	}
+}
+
+func TestPathCacheHitReports(t *testing.T) {
+	// the second subpath cancels the first under nonzero
+	svg := `<svg><path d="M0 0 L10 0 L10 10 L0 10 Z M0 0 L0 10 L10 10 L10 0 Z" fill="#000000"/></svg>`
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	opts.Metrics = &Metrics{}
+	opts.PathCache = NewPathCache(16)
+	convert(t, svg, opts)
+	first := *opts.Metrics
+	convert(t, svg, opts)
+	if opts.PathCache.Hits != 1 {
+		t.Fatalf("got %d hits, want the second conversion to hit", opts.PathCache.Hits)
+	}
+	if len(warnings) != 2 || warnings[0] != warnings[1] {
+		t.Errorf("got warnings %q, want the hit to warn like the miss", warnings)
+	}
+	if opts.Metrics.Linearize != 2*first.Linearize || opts.Metrics.Triangulate != 2*first.Triangulate {
+		t.Errorf("got metrics %+v after %+v, want the hit to add the same timings", *opts.Metrics, first)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// colorKeywords are the basic css color names, the rest of the x11 names
+// aren't known
+var colorKeywords = map[string]string{
+	"black":   "#000000",
+	"silver":  "#c0c0c0",
+	"gray":    "#808080",
+	"grey":    "#808080",
+	"white":   "#ffffff",
+	"maroon":  "#800000",
+	"red":     "#ff0000",
+	"purple":  "#800080",
+	"fuchsia": "#ff00ff",
+	"green":   "#008000",
+	"lime":    "#00ff00",
+	"olive":   "#808000",
+	"yellow":  "#ffff00",
+	"navy":    "#000080",
+	"blue":    "#0000ff",
+	"teal":    "#008080",
+	"aqua":    "#00ffff",
+	"orange":  "#ffa500",
+}
+
+var colorFunctionParser = regexp.MustCompile(`(?i)^(rgba?|hsla?)\(([^)]*)\)$`)
+
+// parseColorFunction reads the arguments of rgb(), rgba(), hsl() or hsla(),
+// separated by commas or by spaces with the alpha after a slash. The a forms
+// are aliases that also accept three arguments.
+func parseColorFunction(name, args string) (c Color, err error) {
+	fields := strings.FieldsFunc(args, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == '/'
+	})
+	if len(fields) != 3 && len(fields) != 4 {
+		return c, fmt.Errorf("%s takes 3 or 4 arguments, got '%s'", name, args)
+	}
+
+	// number reads a plain number or a percentage of full
+	number := func(s string, full float64) (float64, error) {
+		if p := strings.TrimSuffix(s, "%"); p != s {
+			f, err := strconv.ParseFloat(p, 64)
+			return f / 100 * full, err
+		}
+		return strconv.ParseFloat(s, 64)
+	}
+	clamp := func(v float64) float64 {
+		return math.Max(0, math.Min(1, v))
+	}
+
+	c.A = 1
+	if len(fields) == 4 {
+		if c.A, err = number(fields[3], 1); err != nil {
+			return
+		}
+		c.A = clamp(c.A)
+	}
+
+	var v [3]float64
+	if strings.HasPrefix(name, "rgb") {
+		for i := range v {
+			if v[i], err = number(fields[i], 255); err != nil {
+				return
+			}
+		}
+		c.R, c.G, c.B = clamp(v[0]/255), clamp(v[1]/255), clamp(v[2]/255)
+		return
+	}
+
+	if v[0], err = strconv.ParseFloat(strings.TrimSuffix(fields[0], "deg"), 64); err != nil {
+		return
+	}
+	for i := 1; i < 3; i++ {
+		if !strings.HasSuffix(fields[i], "%") {
+			return c, fmt.Errorf("%s saturation and lightness must be percentages, got '%s'", name, args)
+		}
+		if v[i], err = number(fields[i], 1); err != nil {
+			return
+		}
+	}
+	c.R, c.G, c.B = hslToRGB(v[0], clamp(v[1]), clamp(v[2]))
+	return
+}
+
+// hslToRGB converts a hue in degrees, which wraps around, and a saturation
+// and lightness in [0, 1]
+func hslToRGB(h, s, l float64) (r, g, b float64) {
+	h = math.Mod(math.Mod(h, 360)+360, 360) / 60
+	chroma := (1 - math.Abs(2*l-1)) * s
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+	switch int(h) {
+	case 0:
+		r, g = chroma, x
+	case 1:
+		r, g = x, chroma
+	case 2:
+		g, b = chroma, x
+	case 3:
+		g, b = x, chroma
+	case 4:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	m := l - chroma/2
+	return r + m, g + m, b + m
+}
+
+// colorByte is a channel clamped and rounded to 0-255
+func colorByte(v float64) int {
+	return int(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// Hex formats c as #rrggbb, or #rrggbbaa when it isn't opaque
+func (c Color) Hex() string {
+	if c.A < 1 {
+		return fmt.Sprintf("#%02x%02x%02x%02x", colorByte(c.R), colorByte(c.G), colorByte(c.B), colorByte(c.A))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", colorByte(c.R), colorByte(c.G), colorByte(c.B))
+}
+
+// RGBA makes Color a color.Color, returning the alpha premultiplied 16 bit
+// channels
+func (c Color) RGBA() (r, g, b, a uint32) {
+	channel := func(v float64) uint32 {
+		return uint32(math.Round(math.Max(0, math.Min(1, v)) * 0xffff))
+	}
+	a = channel(c.A)
+	return channel(c.R) * a / 0xffff, channel(c.G) * a / 0xffff, channel(c.B) * a / 0xffff, a
+}
+
+// MarshalJSON writes colors as hex strings, see Options.FloatColors for the
+// component form
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Hex())
+}
+
+// UnmarshalJSON reads back the hex strings MarshalJSON writes, and the
+// components written with Options.FloatColors
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return json.Unmarshal(data, (*floatColor)(c))
+	}
+	parsed, err := ParseColor(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// floatColor encodes with the r, g, b and a fields of Color
+type floatColor Color
+
+// floatColorPolygon is a Polygon whose fill and vertex colors encode as
+// components, the fill comes first as it does in Polygon
+type floatColorPolygon struct {
+	Fill floatColor `json:"fill"`
+	Polygon
+	PerVertexColors [][]floatColor `json:"perVertexColors,omitempty"`
+}
+
+// jsonPolygon is the value encoded for p in json output
+func jsonPolygon(p Polygon, opts Options) interface{} {
+	if !opts.FloatColors {
+		return p
+	}
+	ret := floatColorPolygon{Fill: floatColor(p.Fill), Polygon: p}
+	for _, colors := range p.PerVertexColors {
+		ret.PerVertexColors = append(ret.PerVertexColors, Map(colors, func(c Color) floatColor {
+			return floatColor(c)
+		}))
+	}
+	return ret
+}
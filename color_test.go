@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestColorMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(Color{R: 1, G: 0, B: 0, A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"#ff0000"` {
+		t.Errorf("got %s, want \"#ff0000\"", b)
+	}
+}
+
+func TestParseColorHex(t *testing.T) {
+	for _, c := range []struct {
+		in, hex string
+	}{
+		{"#ff0000", "#ff0000"},
+		{"#f00", "#ff0000"},
+		{"#fff", "#ffffff"},
+		{"#00000080", "#00000080"},
+		{"green", "#008000"},
+		{" Navy ", "#000080"},
+		{"rgb(255,0,0)", "#ff0000"},
+		{"rgba(0, 0, 255, 0.5)", "#0000ff80"},
+		{"hsl(120,100%,50%)", "#00ff00"},
+		{"hsl(480, 100%, 50%)", "#00ff00"},
+		{"hsla(0,100%,50%,0.5)", "#ff000080"},
+	} {
+		col, err := ParseColor(c.in)
+		if err != nil {
+			t.Errorf("%s: %v", c.in, err)
+		} else if col.Hex() != c.hex {
+			t.Errorf("%s formats as %s, want %s", c.in, col.Hex(), c.hex)
+		}
+	}
+}
+
+func TestPolygonJSONRoundTrip(t *testing.T) {
+	polys := []Polygon{{
+		ID:        "a",
+		Fill:      Color{R: 1, G: 0, B: 0, A: 1},
+		Exterior:  []Point{{0, 0}, {1, 0}, {0, 1}},
+		Triangles: []Triangle{{0, 1, 2}},
+	}}
+	b, err := json.Marshal(polys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back []Polygon
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatalf("decoding %s: %v", b, err)
+	}
+	if !reflect.DeepEqual(back, polys) {
+		t.Errorf("got %+v back, want %+v", back, polys)
+	}
+
+	var c Color
+	if err := json.Unmarshal([]byte(`{"r":0,"g":1,"b":0,"a":0.5}`), &c); err != nil {
+		t.Fatal(err)
+	} else if c != (Color{G: 1, A: 0.5}) {
+		t.Errorf("float components decode to %+v", c)
+	}
+}
+
+func TestFloatVertexColors(t *testing.T) {
+	p := Polygon{
+		Fill:            Color{R: 1, A: 1},
+		Exterior:        []Point{{0, 0}, {1, 0}, {0, 1}},
+		Triangles:       []Triangle{{0, 1, 2}},
+		PerVertexColors: [][]Color{{{R: 1, A: 1}, {G: 0.5, A: 1}, {B: 1, A: 0.25}}},
+	}
+	opts := DefaultOptions()
+	opts.FloatColors = true
+	b, err := json.Marshal(jsonPolygon(p, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		PerVertexColors [][]map[string]float64 `json:"perVertexColors"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		t.Fatalf("vertex colors in %s aren't components: %v", b, err)
+	}
+	var back Polygon
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatalf("decoding %s: %v", b, err)
+	}
+	if !reflect.DeepEqual(back, p) {
+		t.Errorf("got %+v back, want %+v", back, p)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DifferenceKind says how a polygon differs between two sets
+type DifferenceKind string
+
+const (
+	DifferenceAdded   DifferenceKind = "added"
+	DifferenceRemoved DifferenceKind = "removed"
+	DifferenceChanged DifferenceKind = "changed"
+)
+
+// Difference is a polygon found in only one of the sets compared by
+// ComparePolygons, or a matched pair that doesn't agree
+type Difference struct {
+	Kind DifferenceKind
+	// index of the polygon in each set, -1 for the set it is missing from
+	A, B int
+	// what disagrees between a changed pair
+	Details []string
+}
+
+func (d Difference) String() string {
+	switch d.Kind {
+	case DifferenceAdded:
+		return fmt.Sprintf("polygon %d added", d.B)
+	case DifferenceRemoved:
+		return fmt.Sprintf("polygon %d removed", d.A)
+	}
+	return fmt.Sprintf("polygon %d changed to %d: %s", d.A, d.B, strings.Join(d.Details, ", "))
+}
+
+// ComparePolygons pairs the polygons of a with those of b and reports the
+// ones left unpaired and the pairs that differ. Polygons are paired by id when
+// both have the same one, otherwise by how closely their bounds agree, and
+// only when their bounds overlap. Vertices may move by tolerance before they
+// count as changed, fills are compared as the colors they are written as.
+func ComparePolygons(a, b []Polygon, tolerance float64) (diffs []Difference) {
+	type candidate struct {
+		i, j   int
+		sameID bool
+		cost   float64
+	}
+	var candidates []candidate
+	for i, p := range a {
+		pb := p.Bounds()
+		for j, q := range b {
+			qb := q.Bounds()
+			if pb.Min.X > qb.Max.X+tolerance || qb.Min.X > pb.Max.X+tolerance ||
+				pb.Min.Y > qb.Max.Y+tolerance || qb.Min.Y > pb.Max.Y+tolerance {
+				continue
+			}
+			cost := math.Max(
+				math.Max(math.Abs(pb.Min.X-qb.Min.X), math.Abs(pb.Min.Y-qb.Min.Y)),
+				math.Max(math.Abs(pb.Max.X-qb.Max.X), math.Abs(pb.Max.Y-qb.Max.Y)))
+			cost += math.Abs(math.Abs(Ring(p.Exterior).Area())-math.Abs(Ring(q.Exterior).Area())) / 2
+			candidates = append(candidates, candidate{i, j, p.ID != "" && p.ID == q.ID, cost})
+		}
+	}
+	// the best pairs are taken first so a poor match can't steal a polygon
+	sort.SliceStable(candidates, func(x, y int) bool {
+		if candidates[x].sameID != candidates[y].sameID {
+			return candidates[x].sameID
+		}
+		return candidates[x].cost < candidates[y].cost
+	})
+
+	matchA, matchB := make([]int, len(a)), make([]int, len(b))
+	for i := range matchA {
+		matchA[i] = -1
+	}
+	for j := range matchB {
+		matchB[j] = -1
+	}
+	for _, c := range candidates {
+		if matchA[c.i] < 0 && matchB[c.j] < 0 {
+			matchA[c.i], matchB[c.j] = c.j, c.i
+		}
+	}
+
+	for i, j := range matchA {
+		if j < 0 {
+			diffs = append(diffs, Difference{Kind: DifferenceRemoved, A: i, B: -1})
+		} else if details := comparePolygon(a[i], b[j], tolerance); len(details) > 0 {
+			diffs = append(diffs, Difference{Kind: DifferenceChanged, A: i, B: j, Details: details})
+		}
+	}
+	for j, i := range matchB {
+		if i < 0 {
+			diffs = append(diffs, Difference{Kind: DifferenceAdded, A: -1, B: j})
+		}
+	}
+	return
+}
+
+// comparePolygon describes every way q differs from p
+func comparePolygon(p, q Polygon, tolerance float64) (details []string) {
+	if p.ID != q.ID {
+		details = append(details, fmt.Sprintf("id '%s' became '%s'", p.ID, q.ID))
+	}
+	if p.Label != q.Label {
+		details = append(details, fmt.Sprintf("label '%s' became '%s'", p.Label, q.Label))
+	}
+	if p.Fill.Hex() != q.Fill.Hex() {
+		details = append(details, fmt.Sprintf("fill %s became %s", p.Fill.Hex(), q.Fill.Hex()))
+	}
+
+	details = append(details, compareRing("exterior", p.Exterior, q.Exterior, tolerance)...)
+	if len(p.Holes) != len(q.Holes) {
+		details = append(details, fmt.Sprintf("%d holes became %d", len(p.Holes), len(q.Holes)))
+	} else {
+		for i := range p.Holes {
+			details = append(details, compareRing(fmt.Sprintf("hole %d", i), p.Holes[i], q.Holes[i], tolerance)...)
+		}
+	}
+	if len(p.Steiner) != len(q.Steiner) {
+		details = append(details, fmt.Sprintf("%d steiner points became %d", len(p.Steiner), len(q.Steiner)))
+	}
+
+	if len(p.Triangles) != len(q.Triangles) {
+		details = append(details, fmt.Sprintf("%d triangles became %d", len(p.Triangles), len(q.Triangles)))
+	} else if changed := compareTriangles(p, q, tolerance); changed > 0 {
+		details = append(details, fmt.Sprintf("%d triangles changed", changed))
+	}
+	return
+}
+
+// compareRing matches the vertices of two rings, which may start at different
+// vertices, and reports how many moved further than tolerance
+func compareRing(name string, r, s []Point, tolerance float64) []string {
+	if len(r) != len(s) {
+		return []string{fmt.Sprintf("%s has %d vertices instead of %d", name, len(s), len(r))}
+	} else if len(r) == 0 {
+		return nil
+	}
+	dist := func(p, q Point) float64 { return math.Hypot(q.X-p.X, q.Y-p.Y) }
+
+	// start s at the vertex closest to the start of r
+	shift := 0
+	for i := range s {
+		if dist(r[0], s[i]) < dist(r[0], s[shift]) {
+			shift = i
+		}
+	}
+	moved, furthest := 0, 0.
+	for i := range r {
+		if d := dist(r[i], Ring(s).At(i+shift)); d > tolerance {
+			moved++
+			furthest = math.Max(furthest, d)
+		}
+	}
+	if moved == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s has %d vertices moved by up to %g", name, moved, furthest)}
+}
+
+// compareTriangles counts the triangles of p without one in q at the same
+// place, corners within tolerance in any rotation of their order
+func compareTriangles(p, q Polygon, tolerance float64) (changed int) {
+	pv, qv := p.Vertices(), q.Vertices()
+	corners := func(v []Point, t Triangle) (c [3]Point, ok bool) {
+		for k, i := range t {
+			if i < 0 || i >= len(v) {
+				return c, false
+			}
+			c[k] = v[i]
+		}
+		return c, true
+	}
+	near := func(c, d [3]Point) bool {
+		for shift := 0; shift < 3; shift++ {
+			same := true
+			for k := range c {
+				e := d[(k+shift)%3]
+				if math.Hypot(c[k].X-e.X, c[k].Y-e.Y) > tolerance {
+					same = false
+					break
+				}
+			}
+			if same {
+				return true
+			}
+		}
+		return false
+	}
+
+	used := make([]bool, len(q.Triangles))
+	for _, t := range p.Triangles {
+		c, ok := corners(pv, t)
+		found := false
+		for j, u := range q.Triangles {
+			if used[j] {
+				continue
+			}
+			if d, ok2 := corners(qv, u); ok && ok2 && near(c, d) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			changed++
+		}
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComparePerturbedCopy(t *testing.T) {
+	a := convert(t, `<svg>
+<path id="donut" d="M0 0 L10 0 L10 10 L0 10 Z M4 4 L4 6 L6 6 L6 4 Z" fill="#000000"/>
+<rect x="20" width="5" height="5" fill="#ff0000"/>
+</svg>`, DefaultOptions())
+	b := make([]Polygon, len(a))
+	for i, p := range a {
+		b[len(a)-1-i] = p.Clone()
+	}
+	for i := range b {
+		for j := range b[i].Exterior {
+			b[i].Exterior[j].X += 1e-4
+			b[i].Exterior[j].Y -= 1e-4
+		}
+	}
+
+	if diffs := ComparePolygons(a, b, 1e-3); len(diffs) != 0 {
+		t.Errorf("got %v within the tolerance, want none", diffs)
+	}
+	diffs := ComparePolygons(a, b, 1e-6)
+	if len(diffs) != len(a) {
+		t.Fatalf("got %v, want every polygon changed", diffs)
+	}
+	for _, d := range diffs {
+		if d.Kind != DifferenceChanged || d.B != len(a)-1-d.A || !strings.Contains(d.String(), "exterior has") {
+			t.Errorf("got %v, want polygon %d paired with %d and its exterior moved", d, d.A, len(a)-1-d.A)
+		}
+	}
+
+	b[0].Fill = Color{G: 1, A: 1}
+	diffs = ComparePolygons(a, b[:1], 1e-3)
+	if len(diffs) != 2 {
+		t.Fatalf("got %v, want a removed and a recolored polygon", diffs)
+	}
+	for _, d := range diffs {
+		if d.Kind == DifferenceRemoved {
+			continue
+		}
+		if d.Kind != DifferenceChanged || len(d.Details) != 1 || !strings.Contains(d.Details[0], "#00ff00") {
+			t.Errorf("got %v, want only the fill changed", d)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parseDashArray reads a stroke-dasharray, an odd list is repeated to make it
+// even. nil means a solid stroke.
+func parseDashArray(s string) ([]float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return nil, nil
+	}
+	var dashes []float64
+	total := 0.
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+		d, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("negative stroke-dasharray length in '%s'", s)
+		}
+		dashes = append(dashes, d)
+		total += d
+	}
+	// a pattern of nothing but gaps of zero is drawn solid
+	if total == 0 {
+		return nil, nil
+	}
+	if len(dashes)%2 == 1 {
+		dashes = append(dashes, dashes...)
+	}
+	return dashes, nil
+}
+
+// dashes are walked in steps of at least this fraction of the line, tinier
+// ones would take forever or stop advancing once lost in the rounding
+const minDashFraction = 1e-6
+
+// DashPolyline splits the line through points into the pieces drawn by the
+// dash pattern, starting offset along the pattern. Closed lines include the
+// segment back to the first point.
+func DashPolyline(points []Point, closed bool, dashes []float64, offset float64) (ret [][]Point) {
+	if len(points) < 2 || len(dashes) == 0 {
+		return nil
+	}
+	if closed {
+		points = append(append([]Point(nil), points...), points[0])
+	}
+
+	total := 0.
+	for _, d := range dashes {
+		total += d
+	}
+	minDash := polylineLength(points, false) * minDashFraction
+	// find where in the pattern the line starts
+	i, rem := 0, dashes[0]
+	for phase := math.Mod(math.Mod(offset, total)+total, total); phase > 0; {
+		if phase < rem {
+			rem -= phase
+			break
+		}
+		phase -= rem
+		i = (i + 1) % len(dashes)
+		rem = dashes[i]
+	}
+
+	var current []Point
+	if i%2 == 0 {
+		current = []Point{points[0]}
+	}
+	emit := func() {
+		if len(current) > 1 {
+			ret = append(ret, current)
+		}
+		current = nil
+	}
+	for k := 1; k < len(points); k++ {
+		a, b := points[k-1], points[k]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		t := 0.
+		for length-t > rem {
+			t += rem
+			p := a.Lerp(b, t/length)
+			if i%2 == 0 {
+				current = append(current, p)
+				emit()
+			} else {
+				current = []Point{p}
+			}
+			i = (i + 1) % len(dashes)
+			rem = math.Max(dashes[i], minDash)
+		}
+		rem -= length - t
+		if i%2 == 0 {
+			current = append(current, b)
+		}
+	}
+	emit()
+	return
+}
+
+// polylineLength is the length of the line through points, closed lines
+// include the segment back to the first point
+func polylineLength(points []Point, closed bool) (length float64) {
+	for i := 1; i < len(points); i++ {
+		length += math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+	}
+	if closed && len(points) > 1 {
+		first, last := points[0], points[len(points)-1]
+		length += math.Hypot(first.X-last.X, first.Y-last.Y)
+	}
+	return
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDashPolyline(t *testing.T) {
+	line := []Point{{X: 0, Y: 0}, {X: 100, Y: 0}}
+	dashes := DashPolyline(line, false, []float64{5, 5}, 0)
+	if len(dashes) != 10 {
+		t.Fatalf("got %d dashes, want 10", len(dashes))
+	}
+	for i, d := range dashes {
+		start, end := float64(10*i), float64(10*i+5)
+		if len(d) != 2 || math.Abs(d[0].X-start) > 1e-9 || math.Abs(d[1].X-end) > 1e-9 {
+			t.Errorf("dash %d is %v, want %g to %g", i, d, start, end)
+		}
+	}
+}
+
+func TestDashPolylineTinyDashes(t *testing.T) {
+	line := []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+	for _, pattern := range [][]float64{{1e-17, 1e-17}, {0, 0.5}} {
+		dashes := DashPolyline(line, false, pattern, 0)
+		if max := int(2 / minDashFraction); len(dashes) > max {
+			t.Errorf("pattern %v gives %d dashes, more than %d", pattern, len(dashes), max)
+		}
+	}
+}
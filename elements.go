@@ -0,0 +1,21 @@
+package main
+
+// containerElements are the only elements whose children are walked, so the
+// contents of anything unrecognised can't be mistaken for shapes
+// TODO: a switch should only draw its first child that applies
+var containerElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "symbol": true, "use": true, "a": true, "switch": true,
+}
+
+// shapeElements are the leaves converted to polygons
+var shapeElements = map[string]bool{
+	"polygon": true, "polyline": true, "rect": true, "path": true,
+}
+
+// nonRenderingElements draw nothing themselves, they are passed over without
+// being reported as skipped
+var nonRenderingElements = map[string]bool{
+	"title": true, "desc": true, "metadata": true, "style": true, "script": true,
+	"linearGradient": true, "radialGradient": true, "stop": true,
+	"clipPath": true, "mask": true, "filter": true, "marker": true, "pattern": true,
+}
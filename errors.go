@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errors returned by SVGDReader, wrapped in a ParseError carrying the position
+var (
+	ErrUnexpectedEOF   = errors.New("unexpected end of path data")
+	ErrInvalidCommand  = errors.New("invalid path command")
+	ErrMalformedNumber = errors.New("malformed number")
+	ErrInvalidFlag     = errors.New("invalid arc flag")
+	ErrMissingMoveto   = errors.New("path does not start with a moveto")
+	ErrNoProgress      = errors.New("parser consumed no input")
+	ErrDecimalComma    = errors.New("comma between digits may be a decimal comma")
+)
+
+// ParseError is a failure at a byte offset into the path data, Offset is -1
+// when the reader can't tell where it is
+type ParseError struct {
+	Offset int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("path data: %v", e.Err)
+	}
+	return fmt.Sprintf("path data offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ElementErrors is returned alongside the polygons that did convert when
+// Options.SkipInvalid lets conversion continue past failing elements
+type ElementErrors []error
+
+func (e ElementErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d elements failed to convert, the first: %v", len(e), e[0])
+}
+
+// LimitError is returned when a document goes over one of the limits set in
+// Options
+type LimitError struct {
+	// what was counted, like elements
+	What string
+	Max  int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("document has more than the %d %s allowed", e.Max, e.What)
+}
+
+// limits counts what the Options limits apply to across every walk of a
+// document, markers and symbols included
+type limits struct {
+	elements int
+	vertices int
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// parseStrict parses path data the way a strict conversion does
+func parseStrict(d string) (SVGDParts, error) {
+	r := NewSVGDReader(strings.NewReader(d))
+	r.Strict = true
+	return r.Parse()
+}
+
+func TestParseErrors(t *testing.T) {
+	for d, want := range map[string]error{
+		"M1":                  ErrUnexpectedEOF,
+		"M0 0 L1 1 L2":        ErrUnexpectedEOF,
+		"M0 0 X1":             ErrInvalidCommand,
+		"M0 0 L1e 2":          ErrMalformedNumber,
+		"M0 0 L- 2":           ErrMalformedNumber,
+		"M0 0 A1 1 0 2 0 5 5": ErrInvalidFlag,
+		"M1,5 2,5":            ErrDecimalComma,
+	} {
+		_, err := parseStrict(d)
+		if !errors.Is(err, want) {
+			t.Errorf("'%s' fails with %v, want %v", d, err, want)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("'%s' fails with %T, want a *ParseError", d, err)
+		}
+	}
+}
+
+func TestElementErrorWrapsParseError(t *testing.T) {
+	opts := DefaultOptions()
+	doc, err := ParseDocument(strings.NewReader(`<svg><path d="M0 0 L10 0 X"/></svg>`), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	_, err = ExtractPolygons(doc, opts)
+	var perr *ParseError
+	if !errors.Is(err, ErrInvalidCommand) || !errors.As(err, &perr) || perr.Offset != 11 {
+		t.Errorf("conversion fails with %v, want the invalid command at offset 11 to come through", err)
+	}
+}
+
+func TestParseErrorOffset(t *testing.T) {
+	for _, c := range []struct {
+		d   string
+		bad string
+	}{
+		{"M0 0 L10 0 X5 5", "X"},
+		{"M0 0\n  L10 0 A1 1 0 2 0 5 5", "2"},
+		{"M0 0 L10 -.e", "-"},
+	} {
+		_, err := parseStrict(c.d)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("'%s' fails with %v, want a *ParseError", c.d, err)
+			continue
+		}
+		if perr.Offset < 0 || perr.Offset >= len(c.d) || c.d[perr.Offset:perr.Offset+1] != c.bad {
+			t.Errorf("'%s' fails at offset %d, want the '%s'", c.d, perr.Offset, c.bad)
+		}
+	}
+}
+
+func TestDecimalComma(t *testing.T) {
+	if _, err := parseStrict("M1,5 2,5"); !errors.Is(err, ErrDecimalComma) {
+		t.Errorf("'M1,5 2,5' fails with %v, want %v", err, ErrDecimalComma)
+	}
+	// a comma next to a space is only a separator
+	if _, err := parseStrict("M1, 5 L2 ,5"); err != nil {
+		t.Errorf("'M1, 5 L2 ,5' fails with %v", err)
+	}
+	// a bad command without a comma among the numbers is just that
+	if _, err := parseStrict("M1 5 2 5"); !errors.Is(err, ErrInvalidCommand) {
+		t.Errorf("'M1 5 2 5' fails with %v, want %v", err, ErrInvalidCommand)
+	}
+}
+
+func TestSkipInvalid(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<rect width="10" height="10"/>
+<rect width="oops" height="10"/>
+<rect x="20" width="10" height="10"/>
+</svg>`), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	if _, err := ExtractPolygons(doc, opts); err == nil {
+		t.Error("the broken rect didn't fail the conversion")
+	}
+
+	opts.SkipInvalid = true
+	polys, err := ExtractPolygons(doc, opts)
+	if len(polys) != 2 {
+		t.Errorf("got %d polygons, want the two good rects", len(polys))
+	}
+	var failed ElementErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || !strings.Contains(err.Error(), "rect") {
+		t.Errorf("failed with %v, want the broken rect reported", err)
+	}
+}
+
+func TestMaxElements(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader("<svg>"+strings.Repeat(`<rect width="1" height="1"/>`, 1000)+"</svg>"), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.MaxElements = 100
+	converted := 0
+	err = WalkPolygons(doc, opts, func(Polygon) error {
+		converted++
+		return nil
+	})
+	var limit *LimitError
+	if !errors.As(err, &limit) || limit.What != "elements" || limit.Max != 100 {
+		t.Errorf("failed with %v, want the element limit", err)
+	}
+	if converted >= 100 {
+		t.Errorf("converted %d rects, want the walk stopped at the limit", converted)
+	}
+}
+
+func TestMaxElementsCountsMarkers(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<defs><marker id="m" markerUnits="userSpaceOnUse">`+strings.Repeat(`<rect width="1" height="1"/>`, 50)+`</marker></defs>
+`+strings.Repeat(`<path d="M0 0 L10 0" fill="none" marker-end="url(#m)"/>`, 5)+`
+</svg>`), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.MaxElements = 100
+	_, err = ExtractPolygons(doc, opts)
+	var limit *LimitError
+	if !errors.As(err, &limit) || limit.What != "elements" {
+		t.Errorf("failed with %v, want the marker contents counted against the element limit", err)
+	}
+}
+
+func TestMaxVerticesCountsMarkers(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<defs><marker id="m" markerUnits="userSpaceOnUse">`+strings.Repeat(`<rect width="1" height="1"/>`, 50)+`</marker></defs>
+`+strings.Repeat(`<path d="M0 0 L10 0" fill="none" marker-end="url(#m)"/>`, 5)+`
+</svg>`), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.MaxVertices = 100
+	// a limit isn't one of the invalid elements that can be skipped
+	opts.SkipInvalid = true
+	vertices := 0
+	err = WalkPolygons(doc, opts, func(p Polygon) error {
+		vertices += len(p.Vertices())
+		return nil
+	})
+	var limit *LimitError
+	if !errors.As(err, &limit) || limit.What != "output vertices" {
+		t.Errorf("failed with %v, want the marker vertices counted against the limit", err)
+	}
+	if vertices > 100 {
+		t.Errorf("got %d vertices, want the walk stopped at the limit", vertices)
+	}
+}
+
+func TestMaxVerticesCountsSymbols(t *testing.T) {
+	var symbols strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&symbols, `<symbol id="s%d"><rect width="1" height="1"/></symbol>`, i)
+	}
+	doc, err := ParseDocument(strings.NewReader("<svg>"+symbols.String()+"</svg>"), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.MaxVertices = 100
+	_, err = ExtractSymbols(doc, opts)
+	var limit *LimitError
+	if !errors.As(err, &limit) || limit.What != "output vertices" {
+		t.Errorf("failed with %v, want the symbols counted together against the limit", err)
+	}
+}
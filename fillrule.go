@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+type FillRule string
+
+const (
+	NonZero FillRule = "nonzero"
+	EvenOdd FillRule = "evenodd"
+)
+
+func ParseFillRule(s string) (FillRule, error) {
+	switch r := FillRule(s); r {
+	case NonZero, EvenOdd:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown fill-rule '%s'", s)
+}
+
+// fillRuleOf resolves the fill-rule of el, falling back to the default in
+// opts and then to nonzero as the spec does
+func fillRuleOf(el *svgparser.Element, opts Options) (FillRule, error) {
+	attr := el.Attributes["fill-rule"]
+	if s := styleValue(el, "fill-rule"); s != "" {
+		attr = s
+	}
+	if attr = strings.TrimSpace(attr); attr != "" && attr != "inherit" {
+		return ParseFillRule(attr)
+	}
+	if opts.FillRule != "" {
+		return opts.FillRule, nil
+	}
+	return NonZero, nil
+}
+
+// fills reports whether a point with the given winding number is painted
+func (r FillRule) fills(winding int) bool {
+	if r == EvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// windingNumber counts how many times ring winds counter-clockwise around p
+func windingNumber(ring []Point, p Point) (w int) {
+	for i := range ring {
+		a, b := ring[i], Ring(ring).At(i+1)
+		if a.Y <= p.Y {
+			if b.Y > p.Y && cross(a, b, p) > 0 {
+				w++
+			}
+		} else if b.Y <= p.Y && cross(a, b, p) < 0 {
+			w--
+		}
+	}
+	return
+}
+
+// fillsInside reports whether rule paints the area just inside ring once every
+// one of rings is taken into account, ring being one of them
+func fillsInside(rings [][]Point, ring []Point, rule FillRule) bool {
+	p, ok := insidePoint(ring)
+	if !ok {
+		return false
+	}
+	w := 0
+	for _, r := range rings {
+		w += windingNumber(r, p)
+	}
+	return rule.fills(w)
+}
+
+// insidePoint returns a point just inside ring, off the middle of its first
+// edge, ok is false when the ring has no area to step into
+func insidePoint(ring []Point) (p Point, ok bool) {
+	if len(ring) < 3 {
+		return
+	}
+	a, b := ring[0], ring[1]
+	length := math.Hypot(b.X-a.X, b.Y-a.Y)
+	if length == 0 {
+		return
+	}
+	side := 1.
+	if Ring(ring).IsClockwise() {
+		side = -1
+	}
+	eps := length * 1e-6 * side
+	return Point{
+		X: (a.X+b.X)/2 - (b.Y-a.Y)/length*eps,
+		Y: (a.Y+b.Y)/2 + (b.X-a.X)/length*eps,
+	}, true
+}
+
+// ClassifyRings sorts the subpath rings of a shape into exteriors and holes by
+// how deeply each is nested, found from the winding number of a point just
+// inside it around every other ring. Rings inside an even number of others
+// are exteriors and the rest holes, so islands inside holes are exteriors
+// again. holes maps an index into exteriors to the indices in rings of the
+// holes directly inside it. Rings without area are left out of both.
+func ClassifyRings(rings [][]Point) (exteriors [][]Point, holes map[int][]int) {
+	depth := make([]int, len(rings))
+	// innermost ring around each ring, -1 for none
+	parent := make([]int, len(rings))
+	valid := make([]bool, len(rings))
+	for i, r := range rings {
+		var p Point
+		p, valid[i] = insidePoint(r)
+		parent[i] = -1
+		if !valid[i] {
+			continue
+		}
+		for j, o := range rings {
+			if j == i || len(o) < 3 || windingNumber(o, p) == 0 {
+				continue
+			}
+			depth[i]++
+			if parent[i] < 0 || math.Abs(Ring(o).Area()) < math.Abs(Ring(rings[parent[i]]).Area()) {
+				parent[i] = j
+			}
+		}
+	}
+
+	// index into exteriors of each ring that is one
+	exterior := make(map[int]int)
+	for i, r := range rings {
+		if valid[i] && depth[i]%2 == 0 {
+			exterior[i] = len(exteriors)
+			exteriors = append(exteriors, r)
+		}
+	}
+	holes = make(map[int][]int)
+	for i := range rings {
+		if !valid[i] || depth[i]%2 == 0 {
+			continue
+		}
+		if e, ok := exterior[parent[i]]; ok {
+			holes[e] = append(holes[e], i)
+		}
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyRings(t *testing.T) {
+	outer := []Point{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	left := []Point{{1, 1}, {1, 4}, {4, 4}, {4, 1}}
+	right := []Point{{6, 6}, {6, 9}, {9, 9}, {9, 6}}
+	// the holes come first so order can't decide what is an exterior
+	exteriors, holes := ClassifyRings([][]Point{left, outer, right})
+	if len(exteriors) != 1 || exteriors[0][1] != outer[1] {
+		t.Fatalf("exteriors = %v, want only the outer ring", exteriors)
+	}
+	if h := holes[0]; len(h) != 2 || h[0] != 0 || h[1] != 2 {
+		t.Errorf("holes of the outer ring = %v, want [0 2]", h)
+	}
+}
+
+func TestFillRuleOption(t *testing.T) {
+	// both squares wind the same way so only evenodd makes a hole
+	const ambiguous = `<path d="M0 0 L10 0 L10 10 L0 10 Z M2 2 L8 2 L8 8 L2 8 Z" fill="#000000"/>`
+	for rule, holes := range map[FillRule]int{"": 0, NonZero: 0, EvenOdd: 1} {
+		opts := DefaultOptions()
+		opts.FillRule = rule
+		polys := convert(t, `<svg>`+ambiguous+`</svg>`, opts)
+		if len(polys) != 1 || len(polys[0].Holes) != holes {
+			t.Errorf("under '%s' got %v, want one polygon with %d holes", rule, polys, holes)
+		}
+	}
+
+	// an attribute on the element wins over the option
+	opts := DefaultOptions()
+	opts.FillRule = EvenOdd
+	polys := convert(t, `<svg>`+strings.Replace(ambiguous, "<path", `<path fill-rule="nonzero"`, 1)+`</svg>`, opts)
+	if len(polys) != 1 || len(polys[0].Holes) != 0 {
+		t.Errorf("got %v, want the element's nonzero to fill the inner square", polys)
+	}
+}
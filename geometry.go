@@ -0,0 +1,205 @@
+package main
+
+import "sort"
+
+// cross is the z component of (a - o) x (b - o), positive when o, a, b turn
+// counter-clockwise
+func cross(o, a, b Point) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+// ConvexHull returns the convex hull of points counter-clockwise starting from
+// the leftmost point, using Andrew's monotone chain. Collinear points
+// on the hull edges are dropped. Fewer than three distinct points are returned
+// sorted.
+func ConvexHull(points []Point) Ring {
+	sorted := append([]Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	sorted = RemoveDuplicates(sorted, func(p, q Point) bool { return p.Equals(q) })
+	if len(sorted) < 3 {
+		return sorted
+	}
+
+	hull := make(Ring, 0, 2*len(sorted))
+	// lower hull
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// upper hull
+	for i, lower := len(sorted)-2, len(hull)+1; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// the last point is the first one again
+	hull = hull[:len(hull)-1]
+
+	// all the points were collinear, keep the two ends
+	if len(hull) < 3 {
+		return Ring{sorted[0], sorted[len(sorted)-1]}
+	}
+	return hull
+}
+
+// SceneHull is the convex hull of every exterior vertex in polys
+func SceneHull(polys []Polygon) Ring {
+	var points []Point
+	for _, p := range polys {
+		points = append(points, p.Exterior...)
+	}
+	return ConvexHull(points)
+}
+
+// Hull is the convex hull of the polygon, holes can't reach outside the
+// exterior so only it is considered
+func (p Polygon) Hull() []Point {
+	return ConvexHull(p.Exterior)
+}
+
+// SplitTriangles returns every triangle of p as a polygon of its own with the
+// same fill
+func (p Polygon) SplitTriangles() []Polygon {
+	ret := make([]Polygon, 0, len(p.Triangles))
+	for _, t := range p.TrianglePoints() {
+		ret = append(ret, Polygon{
+			Fill:      p.Fill,
+			Exterior:  []Point{t[0], t[1], t[2]},
+			Triangles: []Triangle{{0, 1, 2}},
+		})
+	}
+	return ret
+}
+
+// IsSimple reports whether the ring has at least three points and no edge
+// touches another except where neighbours share a vertex. Every pair of edges
+// is checked so this is meant for rings of modest size.
+func (r Ring) IsSimple() bool {
+	r = Ring(dedupeRing(r))
+	n := len(r)
+	if n < 3 {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		e := [2]Point{r[i], r.At(i + 1)}
+		for j := i + 1; j < n; j++ {
+			f := [2]Point{r[j], r.At(j + 1)}
+			touches := edgeIntersections(e, f)
+			var shared *Point
+			if j == i+1 {
+				shared = &e[1]
+			} else if i == 0 && j == n-1 {
+				shared = &e[0]
+			}
+			for _, p := range touches {
+				if shared == nil || !p.Equals(*shared) {
+					return false
+				}
+			}
+			// neighbours folding exactly back over each other touch along
+			// their whole length
+			dot := (e[1].X-e[0].X)*(f[1].X-f[0].X) + (e[1].Y-e[0].Y)*(f[1].Y-f[0].Y)
+			if shared != nil && cross(e[0], e[1], f[0]) == 0 && cross(e[0], e[1], f[1]) == 0 && dot < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SplitTouching cuts the ring wherever it passes through the same vertex
+// twice, like the waist of a figure eight, returning each loop as a ring of
+// its own. Loops too small to have any area are dropped. Rings that cross
+// themselves between vertices are not split.
+func (r Ring) SplitTouching() []Ring {
+	r = Ring(dedupeRing(r))
+	seen := make(map[Point]int)
+	for i, p := range r {
+		j, ok := seen[p]
+		if !ok {
+			seen[p] = i
+			continue
+		}
+		// the loop between the two visits and the rest of the ring around it
+		loop := append(Ring(nil), r[j:i]...)
+		rest := append(append(Ring(nil), r[:j]...), r[i:]...)
+		return append(loop.SplitTouching(), rest.SplitTouching()...)
+	}
+	if len(r) < 3 {
+		return nil
+	}
+	return []Ring{r}
+}
+
+// SplitTouching splits the exterior of p with Ring.SplitTouching into
+// polygons sharing its fill and id, each hole going to the piece it is in.
+// The pieces have no triangles.
+func (p Polygon) SplitTouching() (ret []Polygon) {
+	for _, r := range Ring(p.Exterior).SplitTouching() {
+		ret = append(ret, Polygon{
+			ID:                   p.ID,
+			Label:                p.Label,
+			Fill:                 p.Fill,
+			Exterior:             r,
+			ExteriorWasClockwise: p.ExteriorWasClockwise,
+		})
+	}
+	for i, h := range p.Holes {
+		inside, ok := insidePoint(h)
+		if !ok {
+			continue
+		}
+		for k := range ret {
+			if windingNumber(ret[k].Exterior, inside) != 0 {
+				ret[k].Holes = append(ret[k].Holes, h)
+				if i < len(p.HolesWereClockwise) {
+					ret[k].HolesWereClockwise = append(ret[k].HolesWereClockwise, p.HolesWereClockwise[i])
+				}
+				break
+			}
+		}
+	}
+	return
+}
+
+// Clone returns a deep copy of p sharing none of its slices, so either can be
+// changed in place without affecting the other
+func (p Polygon) Clone() Polygon {
+	dup := func(s []Point) []Point {
+		if s == nil {
+			return nil
+		}
+		return append([]Point(nil), s...)
+	}
+	ret := p
+	ret.Exterior = dup(p.Exterior)
+	ret.Steiner = dup(p.Steiner)
+	if p.Holes != nil {
+		ret.Holes = make([][]Point, len(p.Holes))
+		for i, h := range p.Holes {
+			ret.Holes[i] = dup(h)
+		}
+	}
+	if p.Triangles != nil {
+		ret.Triangles = append([]Triangle(nil), p.Triangles...)
+	}
+	if p.HolesWereClockwise != nil {
+		ret.HolesWereClockwise = append([]bool(nil), p.HolesWereClockwise...)
+	}
+	if p.PerVertexColors != nil {
+		ret.PerVertexColors = make([][]Color, len(p.PerVertexColors))
+		for i, c := range p.PerVertexColors {
+			ret.PerVertexColors[i] = append([]Color(nil), c...)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestConvexHull(t *testing.T) {
+	points := []Point{
+		{X: 5, Y: 5}, {X: 10, Y: 10}, {X: 0, Y: 0}, {X: 2, Y: 7},
+		// on the edges
+		{X: 5, Y: 0}, {X: 10, Y: 5},
+		{X: 10, Y: 0}, {X: 0, Y: 10}, {X: 0, Y: 0},
+	}
+	want := Ring{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	if hull := ConvexHull(points); !reflect.DeepEqual(hull, want) {
+		t.Errorf("got hull %v, want %v", hull, want)
+	}
+
+	scene := SceneHull([]Polygon{
+		{Exterior: []Point{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 5, Y: 5}}},
+		{Exterior: []Point{{X: 10, Y: 10}, {X: 0, Y: 10}, {X: 2, Y: 2}}},
+	})
+	want = Ring{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	if !reflect.DeepEqual(scene, want) {
+		t.Errorf("got scene hull %v, want %v", scene, want)
+	}
+
+	for _, c := range []struct{ points, want []Point }{
+		{[]Point{{X: 1, Y: 1}, {X: 0, Y: 0}}, []Point{{X: 0, Y: 0}, {X: 1, Y: 1}}},
+		{[]Point{{X: 2, Y: 2}, {X: 0, Y: 0}, {X: 1, Y: 1}}, []Point{{X: 0, Y: 0}, {X: 2, Y: 2}}},
+	} {
+		if hull := ConvexHull(c.points); !reflect.DeepEqual([]Point(hull), c.want) {
+			t.Errorf("hull of %v is %v, want %v", c.points, hull, c.want)
+		}
+	}
+}
+
+func TestPolygonHullContainsPoints(t *testing.T) {
+	// a concave arrow whose notch the hull bridges
+	p := Polygon{Exterior: []Point{{X: 0, Y: 0}, {X: 10, Y: 5}, {X: 0, Y: 10}, {X: 3, Y: 5}}}
+	hull := p.Hull()
+	if len(hull) != 3 {
+		t.Errorf("got hull %v, want the three outer corners", hull)
+	}
+	for _, q := range p.Exterior {
+		for i := range hull {
+			if c := cross(hull[i], Ring(hull).At(i+1), q); c < 0 {
+				t.Errorf("%v is outside hull edge %d of %v", q, i, hull)
+			}
+		}
+	}
+}
+
+func TestSplitTriangles(t *testing.T) {
+	polys := convert(t, `<svg><path d="M0 0 L10 0 L10 10 L5 4 L0 10 Z M2 1 L4 1 L3 2 Z" fill="#00ff00" fill-rule="evenodd"/></svg>`, DefaultOptions())
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	p := polys[0]
+	pieces := p.SplitTriangles()
+	if len(pieces) != len(p.Triangles) {
+		t.Fatalf("got %d pieces, want %d", len(pieces), len(p.Triangles))
+	}
+	want, got := math.Abs(Ring(p.Exterior).Area()), 0.
+	for _, h := range p.Holes {
+		want -= math.Abs(Ring(h).Area())
+	}
+	for _, q := range pieces {
+		if len(q.Exterior) != 3 || len(q.Triangles) != 1 || q.Triangles[0] != (Triangle{0, 1, 2}) || q.Fill != p.Fill {
+			t.Errorf("got piece %v, want a lone triangle filled %v", q, p.Fill)
+		}
+		got += math.Abs(Ring(q.Exterior).Area())
+	}
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("pieces cover %g, want %g", got/2, want/2)
+	}
+}
+
+func TestIsSimple(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		ring Ring
+		want bool
+	}{
+		{"square", Ring{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}, true},
+		{"closed square", Ring{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}, {X: 0, Y: 0}}, true},
+		{"bowtie", Ring{{X: 0, Y: 0}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 10}}, false},
+		{"line", Ring{{X: 0, Y: 0}, {X: 10, Y: 0}}, false},
+	} {
+		if got := c.ring.IsSimple(); got != c.want {
+			t.Errorf("%s: IsSimple is %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	original := func() Polygon {
+		return Polygon{
+			ID:                 "donut",
+			Exterior:           []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+			Holes:              [][]Point{{{X: 4, Y: 4}, {X: 4, Y: 6}, {X: 6, Y: 6}}},
+			HolesWereClockwise: []bool{true},
+			Triangles:          []Triangle{{0, 1, 4}, {1, 2, 5}},
+			Steiner:            []Point{{X: 2, Y: 2}},
+			PerVertexColors:    [][]Color{{{R: 1, A: 1}}},
+		}
+	}
+	p := original()
+	c := p.Clone()
+	if !reflect.DeepEqual(c, p) {
+		t.Fatalf("clone %+v differs from %+v", c, p)
+	}
+	c.Exterior[0].X = 99
+	c.Holes[0][0].Y = 99
+	c.HolesWereClockwise[0] = false
+	c.Triangles[0][0] = 3
+	c.Steiner[0].X = 99
+	c.PerVertexColors[0][0].G = 1
+	c.Transform(Translate(5, 5))
+	c.Exterior = append(c.Exterior[:2], Point{X: 1, Y: 1})
+	if !reflect.DeepEqual(p, original()) {
+		t.Errorf("changing the clone changed the original to %+v", p)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// glTF constants
+const (
+	gltfArrayBuffer        = 34962
+	gltfElementArrayBuffer = 34963
+	gltfFloat              = 5126
+	gltfUnsignedInt        = 5125
+	gltfTriangles          = 4
+)
+
+type gltfDocument struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes,omitempty"`
+	Meshes      []gltfMesh       `json:"meshes,omitempty"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors,omitempty"`
+	BufferViews []gltfBufferView `json:"bufferViews,omitempty"`
+	Buffers     []gltfBuffer     `json:"buffers,omitempty"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes,omitempty"`
+}
+
+type gltfNode struct {
+	Mesh int `json:"mesh"`
+}
+
+type gltfMesh struct {
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+	Mode       int            `json:"mode"`
+}
+
+type gltfMaterial struct {
+	PBR         gltfPBR `json:"pbrMetallicRoughness"`
+	AlphaMode   string  `json:"alphaMode"`
+	DoubleSided bool    `json:"doubleSided"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor [4]float64 `json:"baseColorFactor"`
+	MetallicFactor  float64    `json:"metallicFactor"`
+	RoughnessFactor float64    `json:"roughnessFactor"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri"`
+}
+
+// GLTFWriter writes a glTF 2.0 document with its buffer embedded as a data
+// uri. Every polygon is a primitive of a single mesh, polygons sharing a fill
+// share a material.
+type GLTFWriter struct {
+	w         io.Writer
+	opts      Options
+	doc       gltfDocument
+	buf       bytes.Buffer
+	materials map[gltfMaterialKey]int
+}
+
+type gltfMaterialKey struct {
+	color Color
+	blend bool
+}
+
+func NewGLTFWriter(w io.Writer, opts Options) *GLTFWriter {
+	return &GLTFWriter{
+		w:    w,
+		opts: opts,
+		doc: gltfDocument{
+			Asset:  gltfAsset{Version: "2.0", Generator: "itsfive"},
+			Scenes: []gltfScene{{Nodes: []int{0}}},
+			Nodes:  []gltfNode{{Mesh: 0}},
+			Meshes: []gltfMesh{{}},
+		},
+		materials: make(map[gltfMaterialKey]int),
+	}
+}
+
+// srgbToLinear converts a color channel to the linear space glTF expects
+func srgbToLinear(c float64) float64 {
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// material returns the index of the material for c, transparent fills and
+// those asked to blend are blended and everything else stays opaque
+func (g *GLTFWriter) material(c Color, blend bool) int {
+	key := gltfMaterialKey{c, blend || c.A < 1}
+	if i, ok := g.materials[key]; ok {
+		return i
+	}
+	m := gltfMaterial{
+		PBR: gltfPBR{
+			BaseColorFactor: [4]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B), c.A},
+			RoughnessFactor: 1,
+		},
+		AlphaMode: "OPAQUE",
+		// a double sided mesh has its own back faces, which can be culled
+		DoubleSided: !g.opts.DoubleSided,
+	}
+	if key.blend {
+		m.AlphaMode = "BLEND"
+	}
+	g.doc.Materials = append(g.doc.Materials, m)
+	g.materials[key] = len(g.doc.Materials) - 1
+	return g.materials[key]
+}
+
+// view appends data to the buffer as a new buffer view
+func (g *GLTFWriter) view(data interface{}, target int) int {
+	offset := g.buf.Len()
+	// writing to a bytes.Buffer can't fail
+	binary.Write(&g.buf, binary.LittleEndian, data)
+	g.doc.BufferViews = append(g.doc.BufferViews, gltfBufferView{
+		ByteOffset: offset,
+		ByteLength: g.buf.Len() - offset,
+		Target:     target,
+	})
+	// views of floats and ints both need four byte alignment
+	for g.buf.Len()%4 != 0 {
+		g.buf.WriteByte(0)
+	}
+	return len(g.doc.BufferViews) - 1
+}
+
+func (g *GLTFWriter) WritePolygon(p Polygon) error {
+	vertices, triangles := g.opts.mesh(p)
+	if len(triangles) == 0 || len(vertices) == 0 {
+		return nil
+	}
+
+	positions := make([]float32, 0, 3*len(vertices))
+	min := []float64{math.Inf(1), math.Inf(1), g.opts.Z}
+	max := []float64{math.Inf(-1), math.Inf(-1), g.opts.Z}
+	for _, v := range vertices {
+		positions = append(positions, float32(v.X), float32(v.Y), float32(g.opts.Z))
+		min[0], min[1] = math.Min(min[0], v.X), math.Min(min[1], v.Y)
+		max[0], max[1] = math.Max(max[0], v.X), math.Max(max[1], v.Y)
+	}
+	indices := make([]uint32, 0, 3*len(triangles))
+	for _, t := range triangles {
+		indices = append(indices, uint32(t[0]), uint32(t[1]), uint32(t[2]))
+	}
+
+	g.doc.Accessors = append(g.doc.Accessors, gltfAccessor{
+		BufferView:    g.view(positions, gltfArrayBuffer),
+		ComponentType: gltfFloat,
+		Count:         len(vertices),
+		Type:          "VEC3",
+		Min:           min,
+		Max:           max,
+	}, gltfAccessor{
+		BufferView:    g.view(indices, gltfElementArrayBuffer),
+		ComponentType: gltfUnsignedInt,
+		Count:         len(indices),
+		Type:          "SCALAR",
+	})
+
+	attributes := map[string]int{"POSITION": len(g.doc.Accessors) - 2}
+	indexAccessor := len(g.doc.Accessors) - 1
+	if uvs := g.opts.uvs(vertices); uvs != nil {
+		coords := make([]float32, 0, 2*len(uvs))
+		for _, uv := range uvs {
+			coords = append(coords, float32(uv.X), float32(uv.Y))
+		}
+		g.doc.Accessors = append(g.doc.Accessors, gltfAccessor{
+			BufferView:    g.view(coords, gltfArrayBuffer),
+			ComponentType: gltfFloat,
+			Count:         len(uvs),
+			Type:          "VEC2",
+		})
+		attributes["TEXCOORD_0"] = len(g.doc.Accessors) - 1
+	}
+
+	material, blend := p.Fill, false
+	if colors := g.opts.meshColors(p, vertices); colors != nil {
+		rgba := make([]float32, 0, 4*len(colors))
+		// a white material leaves the vertex colors as they are
+		material = Color{R: 1, G: 1, B: 1, A: 1}
+		for _, c := range colors {
+			rgba = append(rgba, float32(srgbToLinear(c.R)), float32(srgbToLinear(c.G)), float32(srgbToLinear(c.B)), float32(c.A))
+			blend = blend || c.A < 1
+		}
+		g.doc.Accessors = append(g.doc.Accessors, gltfAccessor{
+			BufferView:    g.view(rgba, gltfArrayBuffer),
+			ComponentType: gltfFloat,
+			Count:         len(colors),
+			Type:          "VEC4",
+		})
+		attributes["COLOR_0"] = len(g.doc.Accessors) - 1
+	}
+
+	mesh := &g.doc.Meshes[0]
+	mesh.Primitives = append(mesh.Primitives, gltfPrimitive{
+		Attributes: attributes,
+		Indices:    indexAccessor,
+		Material:   g.material(material, blend),
+		Mode:       gltfTriangles,
+	})
+	return nil
+}
+
+func (g *GLTFWriter) Close() error {
+	// glTF doesn't allow empty meshes or buffers
+	if len(g.doc.Meshes[0].Primitives) == 0 {
+		g.doc.Scenes[0].Nodes, g.doc.Nodes, g.doc.Meshes = nil, nil, nil
+		return json.NewEncoder(g.w).Encode(g.doc)
+	}
+	g.doc.Buffers = []gltfBuffer{{
+		ByteLength: g.buf.Len(),
+		URI:        "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(g.buf.Bytes()),
+	}}
+	return json.NewEncoder(g.w).Encode(g.doc)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestGLTFAlphaMode(t *testing.T) {
+	polys := convert(t, `<svg>
+<rect width="10" height="10" fill="#000000"/>
+<rect x="20" width="10" height="10" fill="#ff000080"/>
+<rect x="40" width="10" height="10" fill="#00ff00"/>
+</svg>`, DefaultOptions())
+	if len(polys) != 3 {
+		t.Fatalf("got %d polygons, want 3", len(polys))
+	}
+
+	var buf bytes.Buffer
+	w := NewGLTFWriter(&buf, DefaultOptions())
+	for _, p := range polys {
+		if err := w.WritePolygon(p); err != nil {
+			t.Fatalf("writing polygon: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing writer: %v", err)
+	}
+	var doc gltfDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decoding gltf: %v", err)
+	}
+
+	// shapes are told apart by where they are, not the order they come in
+	want := map[float64]string{0: "OPAQUE", 20: "BLEND", 40: "OPAQUE"}
+	for i, prim := range doc.Meshes[0].Primitives {
+		x := polys[i].Bounds().Min.X
+		m := doc.Materials[prim.Material]
+		if m.AlphaMode != want[x] {
+			t.Errorf("shape at %g has alpha mode %s, want %s", x, m.AlphaMode, want[x])
+		}
+	}
+}
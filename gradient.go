@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// gradientColors samples the gradient filling el at every vertex of p, which
+// is in the space m maps the user space of el to. The colors are listed for
+// the exterior, each hole and then the Steiner points, as PerVertexColors
+// holds them. Fills that aren't a linear or radial gradient give nil.
+func gradientColors(el *svgparser.Element, p Polygon, m Matrix, opts Options) ([][]Color, error) {
+	id, ok := parseURLRef(el.Attributes["fill"])
+	if !ok {
+		return nil, nil
+	}
+	g := opts.ids[id]
+	if g == nil || (g.Name != "linearGradient" && g.Name != "radialGradient") {
+		return nil, nil
+	}
+
+	// gradients borrow stops and attributes they don't set from their href
+	chain := []*svgparser.Element{g}
+	for ref := g; len(chain) <= maxPaintServerDepth; {
+		if ref = opts.ids[strings.TrimPrefix(ref.Attributes["href"], "#")]; ref == nil {
+			break
+		}
+		chain = append(chain, ref)
+	}
+	attr := func(name string) string {
+		for _, el := range chain {
+			if v := strings.TrimSpace(el.Attributes[name]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	var stops []*svgparser.Element
+	for _, el := range chain {
+		if stops = el.FindAll("stop"); len(stops) > 0 {
+			break
+		}
+	}
+	if len(stops) == 0 {
+		return nil, nil
+	}
+	offsets, colors, err := parseStops(stops)
+	if err != nil {
+		return nil, err
+	}
+
+	toUser, ok := m.Inverse()
+	if !ok {
+		return nil, nil
+	}
+	gt, err := ParseTransform(attr("gradientTransform"))
+	if err != nil {
+		return nil, err
+	}
+	boundingBox := attr("gradientUnits") != "userSpaceOnUse"
+	if boundingBox {
+		var user []Point
+		for _, v := range p.Exterior {
+			user = append(user, toUser.Apply(v))
+		}
+		box := Ring(user).Bounds()
+		w, h := box.Max.X-box.Min.X, box.Max.Y-box.Min.Y
+		// the bounding box of a line can't be stretched over
+		if w == 0 || h == 0 {
+			return nil, nil
+		}
+		gt = Translate(box.Min.X, box.Min.Y).Multiply(Scale(w, h)).Multiply(gt)
+	}
+	fromUser, ok := gt.Inverse()
+	if !ok {
+		return nil, nil
+	}
+	toGradient := fromUser.Multiply(toUser)
+
+	// a coordinate of the gradient, size being what a percentage is of
+	coord := func(name, def string, size float64) (float64, error) {
+		s := attr(name)
+		if s == "" {
+			s = def
+		}
+		v, unit, err := ParseLength(s)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", describeElement(g), err)
+		}
+		switch {
+		case unit == "%" && boundingBox:
+			return v / 100, nil
+		case unit == "%":
+			return v / 100 * size, nil
+		case boundingBox && unit != "":
+			return 0, fmt.Errorf("%s: %s '%s' can't have a unit with objectBoundingBox", describeElement(g), name, s)
+		}
+		if scale, ok := millimetersPer[unit]; ok {
+			return v * scale / millimetersPer["px"], nil
+		}
+		return 0, fmt.Errorf("%s: %s '%s' has an unsupported unit", describeElement(g), name, s)
+	}
+	vw, vh := opts.viewport[0], opts.viewport[1]
+	diagonal := math.Hypot(vw, vh) / math.Sqrt2
+
+	// position along the gradient of a point in gradient space
+	var at func(q Point) float64
+	if g.Name == "linearGradient" {
+		var c [4]float64
+		for i, a := range []struct {
+			name, def string
+			size      float64
+		}{{"x1", "0%", vw}, {"y1", "0%", vh}, {"x2", "100%", vw}, {"y2", "0%", vh}} {
+			if c[i], err = coord(a.name, a.def, a.size); err != nil {
+				return nil, err
+			}
+		}
+		dx, dy := c[2]-c[0], c[3]-c[1]
+		at = func(q Point) float64 {
+			if dx == 0 && dy == 0 {
+				return 1
+			}
+			return ((q.X-c[0])*dx + (q.Y-c[1])*dy) / (dx*dx + dy*dy)
+		}
+	} else {
+		// the focal point isn't modelled, rings are centered on cx, cy
+		cx, err := coord("cx", "50%", vw)
+		if err != nil {
+			return nil, err
+		}
+		cy, err := coord("cy", "50%", vh)
+		if err != nil {
+			return nil, err
+		}
+		r, err := coord("r", "50%", diagonal)
+		if err != nil {
+			return nil, err
+		}
+		at = func(q Point) float64 {
+			if r <= 0 {
+				return 1
+			}
+			return math.Hypot(q.X-cx, q.Y-cy) / r
+		}
+	}
+
+	spread := attr("spreadMethod")
+	sample := func(v Point) Color {
+		t := at(toGradient.Apply(v))
+		switch spread {
+		case "repeat":
+			t -= math.Floor(t)
+		case "reflect":
+			t = math.Abs(t - 2*math.Floor(t/2+0.5))
+		}
+		return sampleStops(offsets, colors, t)
+	}
+	ring := func(r []Point) []Color {
+		ret := make([]Color, len(r))
+		for i, v := range r {
+			ret[i] = sample(v)
+		}
+		return ret
+	}
+
+	ret := [][]Color{ring(p.Exterior)}
+	for _, h := range p.Holes {
+		ret = append(ret, ring(h))
+	}
+	if len(p.Steiner) > 0 {
+		ret = append(ret, ring(p.Steiner))
+	}
+	return ret, nil
+}
+
+// sampleStops is the color of the piecewise linear gradient at t, padded
+// with the end colors outside of the stops
+func sampleStops(offsets []float64, colors []Color, t float64) Color {
+	if t <= offsets[0] {
+		return colors[0]
+	}
+	for i := 1; i < len(offsets); i++ {
+		if t > offsets[i] {
+			continue
+		}
+		a, b := colors[i-1], colors[i]
+		f := (t - offsets[i-1]) / (offsets[i] - offsets[i-1])
+		return Color{
+			R: a.R + (b.R-a.R)*f,
+			G: a.G + (b.G-a.G)*f,
+			B: a.B + (b.B-a.B)*f,
+			A: a.A + (b.A-a.A)*f,
+		}
+	}
+	return colors[len(colors)-1]
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestGradientCornerColors(t *testing.T) {
+	opts := DefaultOptions()
+	// the solid fill is an average of the stops
+	opts.Warn = func(string) {}
+	polys := convert(t, `<svg>
+<defs><linearGradient id="g" x2="0%" y2="100%">
+<stop offset="0" stop-color="#ff0000"/><stop offset="1" stop-color="#0000ff"/>
+</linearGradient></defs>
+<rect x="10" y="10" width="20" height="10" fill="url(#g)"/>
+</svg>`, opts)
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	p := polys[0]
+	if len(p.PerVertexColors) == 0 || len(p.PerVertexColors[0]) != len(p.Exterior) {
+		t.Fatalf("got colors %v for exterior %v, want one for each vertex", p.PerVertexColors, p.Exterior)
+	}
+	// the gradient runs from the top of the rect to its bottom
+	for i, v := range p.Exterior {
+		want := map[float64]string{10: "#ff0000", 20: "#0000ff"}[v.Y]
+		if got := p.PerVertexColors[0][i].Hex(); got != want {
+			t.Errorf("corner %v is %s, want %s", v, got, want)
+		}
+	}
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math"
+	"mime"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"unicode"
 
@@ -18,13 +26,14 @@ import (
 )
 
 var (
-	coordsSplitter, colorHashParser, floatParser *regexp.Regexp
+	coordsSplitter, colorHashParser, floatParser, urlRefParser *regexp.Regexp
 )
 
 func init() {
 	coordsSplitter = regexp.MustCompile(`[\s,]+`)
-	colorHashParser = regexp.MustCompile(`^#([0-9A-Fa-f]{6})|([0-9A-Fa-f]{3})$`)
+	colorHashParser = regexp.MustCompile(`^#([0-9A-Fa-f]{3,4}|[0-9A-Fa-f]{6}|[0-9A-Fa-f]{8})$`)
 	floatParser = regexp.MustCompile(`^([+-]?([0-9]*[.])?[0-9]+)([^0-9.]|$)`)
+	urlRefParser = regexp.MustCompile(`^url\(\s*#([^)\s]+)\s*\)$`)
 }
 
 type Point struct {
@@ -39,13 +48,44 @@ func (p Point) Equals(q Point) bool {
 	return p.X == q.X && p.Y == q.Y
 }
 
+// Lerp returns the point t of the way from p to q
+func (p Point) Lerp(q Point, t float64) Point {
+	return Point{X: p.X + (q.X-p.X)*t, Y: p.Y + (q.Y-p.Y)*t}
+}
+
+// Round rounds both coordinates to the given number of decimal places.
+// Halfway values round away from zero, so 0.5 becomes 1 and -0.5 becomes -1,
+// and a coordinate rounding to zero is always +0.
+func (p Point) Round(decimals int) Point {
+	scale := math.Pow(10, float64(decimals))
+	return Point{
+		// adding zero turns -0 into +0
+		X: math.Round(p.X*scale)/scale + 0,
+		Y: math.Round(p.Y*scale)/scale + 0,
+	}
+}
+
+// Snap moves p to the nearest multiple of grid, rounding like Round. A grid
+// that isn't positive leaves p unchanged.
+func (p Point) Snap(grid float64) Point {
+	if grid <= 0 {
+		return p
+	}
+	return Point{
+		X: math.Round(p.X/grid)*grid + 0,
+		Y: math.Round(p.Y/grid)*grid + 0,
+	}
+}
+
 type Ring []Point
 
 func (r Ring) At(i int) Point {
-	if len(r) == 0 {
+	n := len(r)
+	if n == 0 {
 		return Point{}
 	}
-	return r[i%len(r)]
+	// go's % keeps the sign of i so wrap negative indices back into range
+	return r[((i%n)+n)%n]
 }
 func (r Ring) Length() int {
 	return len(r)
@@ -64,6 +104,12 @@ func (r Ring) Area() (area float64) {
 	return
 }
 
+// IsClockwise reports whether the ring winds clockwise, that is it has a
+// negative Area
+func (r Ring) IsClockwise() bool {
+	return r.Area() < 0
+}
+
 type Bezier struct {
 	p0, p1, c0, c1 Point
 }
@@ -79,6 +125,90 @@ func (b Bezier) at(t float64) Point {
 	return Point{X: b0.X*(1-t) + b1.X*t, Y: b0.Y*(1-t) + b1.Y*t}
 }
 
+// Split divides the curve at t into the curves before and after it, using de
+// Casteljau's construction
+func (b Bezier) Split(t float64) (Bezier, Bezier) {
+	a0, a1, a2 := b.p0.Lerp(b.c0, t), b.c0.Lerp(b.c1, t), b.c1.Lerp(b.p1, t)
+	b0, b1 := a0.Lerp(a1, t), a1.Lerp(a2, t)
+	mid := b0.Lerp(b1, t)
+	return Bezier{p0: b.p0, c0: a0, c1: b0, p1: mid}, Bezier{p0: mid, c0: b1, c1: a2, p1: b.p1}
+}
+
+// Length measures the curve by halving it until the control polygon of each
+// piece is within tolerance of its chord
+func (b Bezier) Length(tolerance float64) float64 {
+	return b.length(tolerance, 0)
+}
+
+// deep enough for any curve that fits in a float64
+const maxBezierDepth = 32
+
+func (b Bezier) length(tolerance float64, depth int) float64 {
+	dist := func(p, q Point) float64 { return math.Hypot(q.X-p.X, q.Y-p.Y) }
+	chord := dist(b.p0, b.p1)
+	hull := dist(b.p0, b.c0) + dist(b.c0, b.c1) + dist(b.c1, b.p1)
+	if hull-chord <= tolerance || depth >= maxBezierDepth {
+		// the length is between the two, the average converges fastest
+		return (hull + chord) / 2
+	}
+	l, r := b.Split(0.5)
+	return l.length(tolerance/2, depth+1) + r.length(tolerance/2, depth+1)
+}
+
+// Linearize flattens the curve by stepping t by res, each step is split further
+// when maxLength is positive until no segment is longer than it
+func (b Bezier) Linearize(res, maxLength float64) (ret []Point) {
+	prev := 0.
+	step := func(e float64) {
+		if maxLength > 0 && e > 0 {
+			ret = append(ret, b.between(prev, e).divide(maxLength, 0)...)
+		}
+		ret = append(ret, b.at(e))
+		prev = e
+	}
+	for e := 0.; e < 1.0; e += res {
+		step(e)
+	}
+	step(1.)
+	return
+}
+
+// between is the part of the curve from t0 to t1
+func (b Bezier) between(t0, t1 float64) Bezier {
+	_, rest := b.Split(t0)
+	piece, _ := rest.Split((t1 - t0) / (1 - t0))
+	return piece
+}
+
+// divide halves the curve until the control polygon of every piece, which is
+// never shorter than the segment drawn for it, is within maxLength. It
+// returns the points where pieces meet, without the ends of the curve.
+func (b Bezier) divide(maxLength float64, depth int) []Point {
+	if depth >= maxBezierDepth {
+		return nil
+	}
+	dist := func(p, q Point) float64 { return math.Hypot(q.X-p.X, q.Y-p.Y) }
+	if dist(b.p0, b.c0)+dist(b.c0, b.c1)+dist(b.c1, b.p1) <= maxLength {
+		return nil
+	}
+	l, r := b.Split(0.5)
+	return append(append(l.divide(maxLength, depth+1), l.p1), r.divide(maxLength, depth+1)...)
+}
+
+type QuadraticBezier struct {
+	p0, p1, c Point
+}
+
+// ToCubic elevates the quadratic to the cubic tracing the same curve
+func (q QuadraticBezier) ToCubic() Bezier {
+	return Bezier{
+		p0: q.p0,
+		p1: q.p1,
+		c0: Point{X: q.p0.X + 2./3.*(q.c.X-q.p0.X), Y: q.p0.Y + 2./3.*(q.c.Y-q.p0.Y)},
+		c1: Point{X: q.p1.X + 2./3.*(q.c.X-q.p1.X), Y: q.p1.Y + 2./3.*(q.c.Y-q.p1.Y)},
+	}
+}
+
 type Color struct {
 	R float64 `json:"r"`
 	G float64 `json:"g"`
@@ -88,6 +218,10 @@ type Color struct {
 
 type SVGDReader struct {
 	io.RuneScanner
+
+	// report a comma between digits, as in "1,5", as a possible decimal
+	// comma when the path fails to parse
+	Strict bool
 }
 type SVGDCommand rune
 
@@ -103,6 +237,12 @@ const (
 	SVGDRelativeHorizontalCommand SVGDCommand = 'h'
 	SVGDAbsoluteCurveCommand      SVGDCommand = 'C'
 	SVGDRelativeCurveCommand      SVGDCommand = 'c'
+	SVGDAbsoluteQuadraticCommand  SVGDCommand = 'Q'
+	SVGDRelativeQuadraticCommand  SVGDCommand = 'q'
+	SVGDAbsoluteSmoothQuadCommand SVGDCommand = 'T'
+	SVGDRelativeSmoothQuadCommand SVGDCommand = 't'
+	SVGDAbsoluteArcCommand        SVGDCommand = 'A'
+	SVGDRelativeArcCommand        SVGDCommand = 'a'
 	SVGDAbsoluteCloseCommand      SVGDCommand = 'Z'
 	SVGDRelativeCloseCommand      SVGDCommand = 'z'
 )
@@ -112,11 +252,55 @@ var (
 		rune(SVGDAbsoluteMoveCommand), rune(SVGDRelativeMoveCommand), rune(SVGDAbsoluteLineCommand), rune(SVGDRelativeLineCommand),
 		rune(SVGDAbsoluteVerticalCommand), rune(SVGDRelativeVerticalCommand),
 		rune(SVGDAbsoluteHorizontalCommand), rune(SVGDRelativeHorizontalCommand), rune(SVGDAbsoluteCurveCommand), rune(SVGDRelativeCurveCommand),
+		rune(SVGDAbsoluteQuadraticCommand), rune(SVGDRelativeQuadraticCommand),
+		rune(SVGDAbsoluteSmoothQuadCommand), rune(SVGDRelativeSmoothQuadCommand),
+		rune(SVGDAbsoluteArcCommand), rune(SVGDRelativeArcCommand),
 		rune(SVGDAbsoluteCloseCommand), rune(SVGDRelativeCloseCommand),
 	}
 )
 
+// NewSVGDReader wraps s so parse errors report their byte offset
+func NewSVGDReader(s io.RuneScanner) SVGDReader {
+	return SVGDReader{RuneScanner: &countingScanner{RuneScanner: s}}
+}
+
+// countingScanner tracks how many bytes have been read from a RuneScanner
+type countingScanner struct {
+	io.RuneScanner
+	offset, last int
+}
+
+func (s *countingScanner) ReadRune() (r rune, size int, err error) {
+	r, size, err = s.RuneScanner.ReadRune()
+	s.offset += size
+	s.last = size
+	return
+}
+
+func (s *countingScanner) UnreadRune() error {
+	if err := s.RuneScanner.UnreadRune(); err != nil {
+		return err
+	}
+	s.offset -= s.last
+	s.last = 0
+	return nil
+}
+
+// offset is the number of bytes consumed so far, or -1 if the reader wasn't
+// made by NewSVGDReader
+func (r SVGDReader) offset() int {
+	if s, ok := r.RuneScanner.(*countingScanner); ok {
+		return s.offset
+	}
+	return -1
+}
+
+func (r SVGDReader) errorAt(offset int, err error) error {
+	return &ParseError{Offset: offset, Err: err}
+}
+
 func (r SVGDReader) ChompCommand() (SVGDCommand, error) {
+	at := r.offset()
 	if ru, _, err := r.RuneScanner.ReadRune(); err != nil {
 		return SVGDInvalidCommand, err
 	} else if slices.Index(SVGAllCommands, ru) >= 0 {
@@ -124,9 +308,12 @@ func (r SVGDReader) ChompCommand() (SVGDCommand, error) {
 	} else if err := r.RuneScanner.UnreadRune(); err != nil {
 		return SVGDInvalidCommand, fmt.Errorf("could not unread rune: %v", err)
 	}
-	return SVGDInvalidCommand, fmt.Errorf("invalid reader state: no valid command found")
+	return SVGDInvalidCommand, r.errorAt(at, ErrInvalidCommand)
 }
 
+// SVGDPart is one command of path data. Linearize is given the absolute
+// current point, relative parts are offset from it and every point returned is
+// absolute, so absolute and relative commands can follow each other freely.
 type SVGDPart interface {
 	Linearize(start Point, res float64) []Point
 }
@@ -135,7 +322,7 @@ type SVGDAbsoluteMovePart struct {
 	Point
 }
 
-// TODO: how to handle multple paths
+// a move starts a new subpath, see SVGDParts.LinearizeRings
 func (p SVGDAbsoluteMovePart) Linearize(start Point, res float64) []Point {
 	return []Point{p.Point}
 }
@@ -144,7 +331,8 @@ type SVGDRelativeMovePart struct {
 	Point
 }
 
-// TODO: how to handle multple paths
+// relative to the current point, which after a close command is the start of
+// the subpath just closed
 func (p SVGDRelativeMovePart) Linearize(start Point, res float64) []Point {
 	return []Point{start.Add(p.Point)}
 }
@@ -201,26 +389,109 @@ type SVGDAbsoluteCurvePart struct {
 	points [3]Point
 }
 
-func (p SVGDAbsoluteCurvePart) Linearize(start Point, res float64) (ret []Point) {
-	b := Bezier{p0: start, c0: p.points[0], c1: p.points[1], p1: p.points[2]}
-	for e := 0.; e < 1.0; e += res {
-		ret = append(ret, b.at(e))
-	}
-	ret = append(ret, b.at(1.))
-	return
+func (p SVGDAbsoluteCurvePart) Linearize(start Point, res float64) []Point {
+	return p.linearize(start, flattening{res: res})
+}
+
+func (p SVGDAbsoluteCurvePart) linearize(start Point, f flattening) []Point {
+	return Bezier{p0: start, c0: p.points[0], c1: p.points[1], p1: p.points[2]}.Linearize(f.res, f.maxLength)
 }
 
 type SVGDRelativeCurvePart struct {
 	points [3]Point
 }
 
-func (p SVGDRelativeCurvePart) Linearize(start Point, res float64) (ret []Point) {
-	b := Bezier{p0: start, c0: start.Add(p.points[0]), c1: start.Add(p.points[1]), p1: start.Add(p.points[2])}
-	for e := 0.; e < 1.0; e += res {
-		ret = append(ret, b.at(e))
+func (p SVGDRelativeCurvePart) Linearize(start Point, res float64) []Point {
+	return p.linearize(start, flattening{res: res})
+}
+
+func (p SVGDRelativeCurvePart) linearize(start Point, f flattening) []Point {
+	return Bezier{p0: start, c0: start.Add(p.points[0]), c1: start.Add(p.points[1]), p1: start.Add(p.points[2])}.Linearize(f.res, f.maxLength)
+}
+
+// flattening is how curves are turned into points, see the Options of the
+// same names
+type flattening struct {
+	// Resolution
+	res float64
+	// MaxSegmentLength
+	maxLength float64
+	// ArcsAsCubics
+	arcsAsCubics bool
+}
+
+// curvePart is implemented by the cubic and arc commands, which follow every
+// setting of a flattening rather than only its res
+type curvePart interface {
+	linearize(start Point, f flattening) []Point
+}
+
+// quadraticPart is implemented by the quadratic commands so a following T can
+// reflect their control point, prev is the control point of the previous
+// quadratic segment or nil
+type quadraticPart interface {
+	quadratic(start Point, prev *Point) QuadraticBezier
+}
+
+func (q QuadraticBezier) Linearize(res, maxLength float64) []Point {
+	return q.ToCubic().Linearize(res, maxLength)
+}
+
+type SVGDAbsoluteQuadraticPart struct {
+	points [2]Point
+}
+
+func (p SVGDAbsoluteQuadraticPart) quadratic(start Point, prev *Point) QuadraticBezier {
+	return QuadraticBezier{p0: start, c: p.points[0], p1: p.points[1]}
+}
+
+func (p SVGDAbsoluteQuadraticPart) Linearize(start Point, res float64) []Point {
+	return p.quadratic(start, nil).Linearize(res, 0)
+}
+
+type SVGDRelativeQuadraticPart struct {
+	points [2]Point
+}
+
+func (p SVGDRelativeQuadraticPart) quadratic(start Point, prev *Point) QuadraticBezier {
+	return QuadraticBezier{p0: start, c: start.Add(p.points[0]), p1: start.Add(p.points[1])}
+}
+
+func (p SVGDRelativeQuadraticPart) Linearize(start Point, res float64) []Point {
+	return p.quadratic(start, nil).Linearize(res, 0)
+}
+
+// reflectControl is the control point of a smooth segment starting at start,
+// the previous control point reflected through start or start itself
+func reflectControl(start Point, prev *Point) Point {
+	if prev == nil {
+		return start
 	}
-	ret = append(ret, b.at(1.))
-	return
+	return Point{X: 2*start.X - prev.X, Y: 2*start.Y - prev.Y}
+}
+
+type SVGDAbsoluteSmoothQuadraticPart struct {
+	Point
+}
+
+func (p SVGDAbsoluteSmoothQuadraticPart) quadratic(start Point, prev *Point) QuadraticBezier {
+	return QuadraticBezier{p0: start, c: reflectControl(start, prev), p1: p.Point}
+}
+
+func (p SVGDAbsoluteSmoothQuadraticPart) Linearize(start Point, res float64) []Point {
+	return p.quadratic(start, nil).Linearize(res, 0)
+}
+
+type SVGDRelativeSmoothQuadraticPart struct {
+	Point
+}
+
+func (p SVGDRelativeSmoothQuadraticPart) quadratic(start Point, prev *Point) QuadraticBezier {
+	return QuadraticBezier{p0: start, c: reflectControl(start, prev), p1: start.Add(p.Point)}
+}
+
+func (p SVGDRelativeSmoothQuadraticPart) Linearize(start Point, res float64) []Point {
+	return p.quadratic(start, nil).Linearize(res, 0)
 }
 
 type SVGDClosePart struct{}
@@ -229,7 +500,44 @@ func (p SVGDClosePart) Linearize(start Point, res float64) (ret []Point) {
 	return
 }
 
+// commandArity is the number of operands each command takes
+var commandArity = map[SVGDCommand]int{
+	SVGDAbsoluteMoveCommand:       2,
+	SVGDRelativeMoveCommand:       2,
+	SVGDAbsoluteLineCommand:       2,
+	SVGDRelativeLineCommand:       2,
+	SVGDAbsoluteVerticalCommand:   1,
+	SVGDRelativeVerticalCommand:   1,
+	SVGDAbsoluteHorizontalCommand: 1,
+	SVGDRelativeHorizontalCommand: 1,
+	SVGDAbsoluteCurveCommand:      6,
+	SVGDRelativeCurveCommand:      6,
+	SVGDAbsoluteQuadraticCommand:  4,
+	SVGDRelativeQuadraticCommand:  4,
+	SVGDAbsoluteSmoothQuadCommand: 2,
+	SVGDRelativeSmoothQuadCommand: 2,
+	SVGDAbsoluteArcCommand:        7,
+	SVGDRelativeArcCommand:        7,
+	SVGDAbsoluteCloseCommand:      0,
+	SVGDRelativeCloseCommand:      0,
+}
+
+// Arity is the number of operands the command takes, or -1 for an unknown
+// command
+func (c SVGDCommand) Arity() int {
+	if n, ok := commandArity[c]; ok {
+		return n
+	}
+	return -1
+}
+
 func MakePart(cmd SVGDCommand, coords ...float64) (SVGDPart, error) {
+	if n := cmd.Arity(); n < 0 {
+		return nil, fmt.Errorf("invalid command '%c'", cmd)
+	} else if len(coords) != n {
+		return nil, fmt.Errorf("command '%c' takes %d coordinates, got %d", cmd, n, len(coords))
+	}
+
 	switch cmd {
 	case SVGDAbsoluteMoveCommand:
 		return SVGDAbsoluteMovePart{Point: Point{X: coords[0], Y: coords[1]}}, nil
@@ -259,6 +567,32 @@ func MakePart(cmd SVGDCommand, coords ...float64) (SVGDPart, error) {
 			{X: coords[2], Y: coords[3]},
 			{X: coords[4], Y: coords[5]},
 		}}, nil
+	case SVGDAbsoluteQuadraticCommand:
+		return SVGDAbsoluteQuadraticPart{points: [2]Point{
+			{X: coords[0], Y: coords[1]},
+			{X: coords[2], Y: coords[3]},
+		}}, nil
+	case SVGDRelativeQuadraticCommand:
+		return SVGDRelativeQuadraticPart{points: [2]Point{
+			{X: coords[0], Y: coords[1]},
+			{X: coords[2], Y: coords[3]},
+		}}, nil
+	case SVGDAbsoluteSmoothQuadCommand:
+		return SVGDAbsoluteSmoothQuadraticPart{Point: Point{X: coords[0], Y: coords[1]}}, nil
+	case SVGDRelativeSmoothQuadCommand:
+		return SVGDRelativeSmoothQuadraticPart{Point: Point{X: coords[0], Y: coords[1]}}, nil
+	case SVGDAbsoluteArcCommand:
+		return SVGDAbsoluteArcPart{
+			rx: coords[0], ry: coords[1], rotation: coords[2],
+			large: coords[3] != 0, sweep: coords[4] != 0,
+			Point: Point{X: coords[5], Y: coords[6]},
+		}, nil
+	case SVGDRelativeArcCommand:
+		return SVGDRelativeArcPart{
+			rx: coords[0], ry: coords[1], rotation: coords[2],
+			large: coords[3] != 0, sweep: coords[4] != 0,
+			Point: Point{X: coords[5], Y: coords[6]},
+		}, nil
 	case SVGDAbsoluteCloseCommand:
 		fallthrough
 	case SVGDRelativeCloseCommand:
@@ -270,91 +604,161 @@ func MakePart(cmd SVGDCommand, coords ...float64) (SVGDPart, error) {
 
 type SVGDParts []SVGDPart
 
+// Linearize flattens the whole path into a single list of points, where one
+// subpath ends and the next starts are joined. See LinearizeRings to keep them
+// apart.
 func (a SVGDParts) Linearize(res float64) (ret []Point) {
+	for _, r := range a.LinearizeRings(res) {
+		ret = append(ret, r...)
+	}
+	return
+}
+
+// LinearizeRings flattens the path into a list of points for each subpath, a
+// new one starts at every move command so subpaths are never joined by an
+// edge. Closed subpaths end back at their first point.
+func (a SVGDParts) LinearizeRings(res float64) [][]Point {
+	return a.linearizeRings(flattening{res: res})
+}
+
+// linearizeRings is LinearizeRings with the rest of the flattening options
+func (a SVGDParts) linearizeRings(f flattening) (ret [][]Point) {
+	// control point of the previous segment when it was quadratic
+	var control *Point
+	// where the current subpath began, closing it returns there
+	var subpath, last Point
+	var current []Point
 	for _, p := range a {
-		last := Point{}
-		if e := len(ret) - 1; e >= 0 {
-			last = ret[e]
+		switch p.(type) {
+		case SVGDClosePart:
+			current = append(current, subpath)
+			last, control = subpath, nil
+			continue
+		case SVGDAbsoluteMovePart, SVGDRelativeMovePart:
+			if len(current) > 0 {
+				ret = append(ret, current)
+			}
+			subpath = p.Linearize(last, f.res)[0]
+			current = nil
 		}
 
-		ret = append(ret, p.Linearize(last, res)...)
+		var points []Point
+		if q, ok := p.(quadraticPart); ok {
+			b := q.quadratic(last, control)
+			points = b.Linearize(f.res, f.maxLength)
+			control = &b.c
+		} else if c, ok := p.(curvePart); ok {
+			control = nil
+			points = c.linearize(last, f)
+		} else {
+			control = nil
+			points = p.Linearize(last, f.res)
+		}
+		current = append(current, points...)
+		if len(points) > 0 {
+			last = points[len(points)-1]
+		}
+	}
+	if len(current) > 0 {
+		ret = append(ret, current)
 	}
 	return
 }
 
+// Parse reads path data up to the end of the input, a close command ends its
+// subpath and parsing carries on with the next. Whitespace, including the
+// newlines and indentation of pretty printed attributes, may surround any
+// command or operand.
 func (r SVGDReader) Parse() (parts SVGDParts, err error) {
 	cmd := SVGDInvalidCommand
 	var part SVGDPart
-	x, y := 0., 0.
-	c := make([]float64, 6)
+	c := make([]float64, 7)
+	// offset of the last comma found directly between two digits
+	decimalComma := -1
+	last := -1
 	for {
+		// every command consumes input, one that didn't would loop forever
+		start := r.offset()
+		if start >= 0 && start == last {
+			return parts, r.errorAt(start, ErrNoProgress)
+		}
+		last = start
+
 		if _, err = r.ChompSeperator(); err != nil {
-			//TODO: check for the end of the stream
 			return
-		} else if cmd, err = r.ChompCommand(); err != nil {
+		} else if cmd, err = r.ChompCommand(); err == io.EOF {
+			// open paths end without a close command
+			err = nil
+			return
+		} else if errors.Is(err, ErrInvalidCommand) && r.Strict && decimalComma >= 0 {
+			// numbers left over where a command belongs are what "1,5"
+			// meaning 1.5 turns into
+			return parts, r.errorAt(decimalComma, ErrDecimalComma)
+		} else if err != nil {
+			return
+		} else if _, err = r.ChompSeperator(); err != nil {
+			// whitespace may follow the command letter
 			return
 		}
 
-		switch cmd {
-		case SVGDAbsoluteLineCommand:
-			fallthrough
-		case SVGDRelativeLineCommand:
-			fallthrough
-		case SVGDAbsoluteMoveCommand:
-			fallthrough
-		case SVGDRelativeMoveCommand:
-			if x, err = r.ChompNumber(); err != nil {
-				return
-			} else if _, err = r.ChompSeperator(); err != nil {
-				return
-			} else if y, err = r.ChompNumber(); err != nil {
-				return
-			} else if part, err = MakePart(cmd, x, y); err != nil {
-				return
-			}
-			parts = append(parts, part)
-		case SVGDAbsoluteHorizontalCommand:
-			fallthrough
-		case SVGDRelativeHorizontalCommand:
-			fallthrough
-		case SVGDAbsoluteVerticalCommand:
-			fallthrough
-		case SVGDRelativeVerticalCommand:
-			if x, err = r.ChompNumber(); err != nil {
-				return
-			} else if part, err = MakePart(cmd, x); err != nil {
-				return
-			}
-			parts = append(parts, part)
-		case SVGDAbsoluteCurveCommand:
-			fallthrough
-		case SVGDRelativeCurveCommand:
-			for i := range c {
-				if c[i], err = r.ChompNumber(); err != nil {
-					return
-				} else if _, err = r.ChompSeperator(); err != nil {
-					return
-				}
+		arc := cmd == SVGDAbsoluteArcCommand || cmd == SVGDRelativeArcCommand
+		n := cmd.Arity()
+		for i := range c[:n] {
+			// the large arc and sweep flags may be packed without separators
+			if arc && (i == 3 || i == 4) {
+				c[i], err = r.ChompFlag()
+			} else {
+				c[i], err = r.ChompNumber()
 			}
-			if part, err = MakePart(cmd, c...); err != nil {
+			if err != nil {
 				return
 			}
-			parts = append(parts, part)
-		case SVGDAbsoluteCloseCommand:
-			fallthrough
-		case SVGDRelativeCloseCommand:
-			if part, err = MakePart(cmd); err != nil {
-				return
+			at := r.offset()
+			if sep, err := r.ChompSeperator(); err != nil {
+				return parts, err
+			} else if sep == "," && r.peekDigit() {
+				decimalComma = at
 			}
-			parts = append(parts, part)
+		}
+		if part, err = MakePart(cmd, c[:n]...); err != nil {
 			return
 		}
+		parts = append(parts, part)
+	}
+}
+
+// peekDigit reports whether the next rune is a digit without consuming it
+func (r SVGDReader) peekDigit() bool {
+	ru, _, err := r.RuneScanner.ReadRune()
+	if err != nil {
+		return false
+	}
+	r.RuneScanner.UnreadRune()
+	return ru >= '0' && ru <= '9'
+}
+
+// reads a single 0 or 1 arc flag
+func (r SVGDReader) ChompFlag() (float64, error) {
+	at := r.offset()
+	if ru, _, err := r.RuneScanner.ReadRune(); err == io.EOF {
+		return 0, r.errorAt(at, ErrUnexpectedEOF)
+	} else if err != nil {
+		return 0, err
+	} else if ru == '0' {
+		return 0, nil
+	} else if ru == '1' {
+		return 1, nil
 	}
+	return 0, r.errorAt(at, ErrInvalidFlag)
 }
 
-// returns -1.0, 1.0 or 0 on error
+// returns -1.0, 1.0 or 0 on error, a rune that can't start a number is not
+// consumed
 func (r SVGDReader) ChompSign() (float64, error) {
-	if ru, _, err := r.RuneScanner.ReadRune(); err != nil {
+	at := r.offset()
+	if ru, _, err := r.RuneScanner.ReadRune(); err == io.EOF {
+		return 0, r.errorAt(at, ErrUnexpectedEOF)
+	} else if err != nil {
 		return 0, err
 	} else if ru == '+' {
 		return 1, nil
@@ -366,14 +770,20 @@ func (r SVGDReader) ChompSign() (float64, error) {
 			return 0, err
 		}
 		return 1, nil
+	} else if err := r.RuneScanner.UnreadRune(); err != nil {
+		return 0, fmt.Errorf("could not unread rune: %v", err)
 	}
-	return 0, fmt.Errorf("not a number")
+	// left for the caller, it may be the command ending a run of numbers
+	return 0, r.errorAt(at, ErrMalformedNumber)
 }
 
+// separators are optional so running into the end of the input is not an error
 func (r SVGDReader) ChompSeperator() (string, error) {
 	var str []rune
 	for {
-		if ru, _, err := r.RuneScanner.ReadRune(); err != nil {
+		if ru, _, err := r.RuneScanner.ReadRune(); err == io.EOF {
+			return string(str), nil
+		} else if err != nil {
 			return string(str), err
 		} else if unicode.IsSpace(ru) || ru == ',' {
 			str = append(str, ru)
@@ -386,6 +796,7 @@ func (r SVGDReader) ChompSeperator() (string, error) {
 }
 
 func (r SVGDReader) ChompNumber() (float64, error) {
+	at := r.offset()
 	// first get the sign
 	sign := 1.
 	var err error
@@ -398,11 +809,17 @@ func (r SVGDReader) ChompNumber() (float64, error) {
 	var str []rune
 
 	for {
-		if ru, _, err := r.RuneScanner.ReadRune(); err != nil {
+		if ru, _, err := r.RuneScanner.ReadRune(); err == io.EOF {
+			break
+		} else if err != nil {
 			return 0, err
 		} else if ru == '.' {
 			if point {
-				return 0, fmt.Errorf("double decimal point detected")
+				// a second decimal point starts the next number, as in "1.5.5"
+				if err := r.RuneScanner.UnreadRune(); err != nil {
+					return 0, err
+				}
+				break
 			}
 			str = append(str, ru)
 			point = true
@@ -415,10 +832,10 @@ func (r SVGDReader) ChompNumber() (float64, error) {
 		}
 	}
 
-	if len(str) == 0 {
-		return 0, fmt.Errorf("no number found")
+	if len(str) == 0 || string(str) == "." {
+		return 0, r.errorAt(at, ErrMalformedNumber)
 	} else if num, err := strconv.ParseFloat(string(str), 64); err != nil {
-		return 0, err
+		return 0, r.errorAt(at, ErrMalformedNumber)
 	} else {
 		return sign * num, nil
 	}
@@ -432,9 +849,10 @@ func mustParseHex(s string) (x uint64) {
 	return
 }
 
+// mustParseHexColor scales hex digits to 0-1, all f being 1
 func mustParseHexColor(s string) float64 {
-	shifter := 1 << (4 * len(s))
-	return float64(mustParseHex(s)) / float64(shifter)
+	max := 1<<(4*len(s)) - 1
+	return float64(mustParseHex(s)) / float64(max)
 }
 
 func Reverse[K interface{}](s []K) {
@@ -443,6 +861,17 @@ func Reverse[K interface{}](s []K) {
 		s[i], s[j] = s[j], s[i]
 	}
 }
+
+// ReversePermutation reverses s in place and returns the permutation it applied,
+// the element that was at index i is now at perm[i]
+func ReversePermutation[K interface{}](s []K) (perm []int) {
+	Reverse(s)
+	perm = make([]int, len(s))
+	for i := range perm {
+		perm[i] = len(s) - i - 1
+	}
+	return
+}
 func Map[K interface{}, V interface{}](s []K, t func(K) V) (r []V) {
 	for _, k := range s {
 		r = append(r, t(k))
@@ -460,34 +889,53 @@ func RemoveDuplicates[K interface{}](s []K, pred func(K, K) bool) (ret []K) {
 }
 
 func parseHashColor(col string) (c Color, err error) {
-	matches := colorHashParser.FindStringSubmatch(col)
-
-	if matches[0] == "" {
+	matches := colorHashParser.FindStringSubmatch(strings.TrimSpace(col))
+	if matches == nil {
 		err = fmt.Errorf("uknown color format for '%s'", col)
 		return
 	}
 
-	if col := matches[2]; len(col) == 3 {
-		c.R = mustParseHexColor(col[0:1])
-		c.G = mustParseHexColor(col[1:2])
-		c.B = mustParseHexColor(col[2:3])
-		return
-	} else if col := matches[1]; len(col) == 6 {
-		c.R = mustParseHexColor(col[0:2])
-		c.G = mustParseHexColor(col[2:4])
-		c.B = mustParseHexColor(col[4:6])
-		return
+	// one or two digits for each of r, g, b and an optional alpha
+	hex := matches[1]
+	n := 1
+	if len(hex) >= 6 {
+		n = 2
 	}
-
-	//TODO: remove after debugging
-	panic(fmt.Errorf("check the colorHashParser regex because we should never get here"))
+	c.A = 1
+	for i, v := range []*float64{&c.R, &c.G, &c.B, &c.A}[:len(hex)/n] {
+		*v = mustParseHexColor(hex[i*n : (i+1)*n])
+	}
+	return
 }
 
 func ParseColor(col string) (Color, error) {
-	//TODO: add RGB and RGBA colors
+	if matches := colorFunctionParser.FindStringSubmatch(strings.TrimSpace(col)); matches != nil {
+		return parseColorFunction(strings.ToLower(matches[1]), matches[2])
+	}
+	if hex, ok := colorKeywords[strings.ToLower(strings.TrimSpace(col))]; ok {
+		return parseHashColor(hex)
+	}
 	return parseHashColor(col)
 }
 
+// parseURLRef extracts the id from a url(#id) attribute value
+func parseURLRef(s string) (string, bool) {
+	matches := urlRefParser.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// floatAttr parses a numeric attribute, returning def when it is absent
+func floatAttr(el *svgparser.Element, name string, def float64) (float64, error) {
+	s := strings.TrimSpace(el.Attributes[name])
+	if s == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
 func MustParseColor(col string) Color {
 	c, err := ParseColor(col)
 	if err != nil {
@@ -498,124 +946,386 @@ func MustParseColor(col string) Color {
 
 type Triangle [3]int
 
-type Polygon struct {
-	Fill      Color      `json:"fill"` // replace with some sort of color
-	Exterior  []Point    `json:"exterior"`
-	Triangles []Triangle `json:"triangle"`
+// Remap returns the triangle with its indices passed through perm, as returned
+// by ReversePermutation
+func (t Triangle) Remap(perm []int) Triangle {
+	return Triangle{perm[t[0]], perm[t[1]], perm[t[2]]}
 }
 
-func PolygonFromPathElement(el *svgparser.Element, res float64) (*Polygon, error) {
-	if res <= 0 {
-		panic(fmt.Errorf("negative bezier increment"))
-	}
-	var poly Polygon
+// Offset returns the triangle with n added to every index, writers use it to
+// number vertices from their own base
+func (t Triangle) Offset(n int) Triangle {
+	return Triangle{t[0] + n, t[1] + n, t[2] + n}
+}
 
-	var tp []triangolatte.Point
+type Polygon struct {
+	// id of the element the polygon was converted from, if it has one
+	ID string `json:"id,omitempty"`
+	// human readable name of the element from its aria-label or <title>
+	Label    string    `json:"label,omitempty"`
+	Fill     Color     `json:"fill"` // replace with some sort of color
+	Exterior []Point   `json:"exterior"`
+	Holes    [][]Point `json:"holes,omitempty"`
+	// 0-based indices into the exterior followed by each of the holes, see
+	// Vertices and TrianglesFrom
+	Triangles []Triangle `json:"triangle"`
 
-	d := el.Attributes["d"]
+	// winding of each ring as written in the source, before any transform or
+	// reorientation. Clockwise means negative Ring.Area.
+	ExteriorWasClockwise bool   `json:"exteriorWasClockwise,omitempty"`
+	HolesWereClockwise   []bool `json:"holesWereClockwise,omitempty"`
 
-	fmt.Fprintf(os.Stderr, "d attribute: %s\n", d)
+	// points added inside the polygon by RefineTriangulation
+	Steiner []Point `json:"steiner,omitempty"`
 
-	dreader := SVGDReader{strings.NewReader(d)}
+	// when the fill is a gradient, its color at each vertex in lists for the
+	// exterior, each hole and the Steiner points, lined up with Vertices
+	PerVertexColors [][]Color `json:"perVertexColors,omitempty"`
+}
 
-	parts, err := dreader.Parse()
-	if err != nil {
-		return nil, err
+// NormalizeWinding orients the exterior counter-clockwise, or clockwise when
+// asked, and every hole the opposite way as triangolatte expects. Triangles
+// are remapped onto the reversed rings.
+func (p *Polygon) NormalizeWinding(clockwise bool) {
+	perm := make([]int, 0, len(p.Vertices()))
+	reversed := false
+	orient := func(ring []Point, cw bool) {
+		offset := len(perm)
+		if len(ring) > 2 && Ring(ring).IsClockwise() != cw {
+			for _, i := range ReversePermutation(ring) {
+				perm = append(perm, offset+i)
+			}
+			reversed = true
+			return
+		}
+		for i := range ring {
+			perm = append(perm, offset+i)
+		}
 	}
-
-	// reverse it
-	// Reverse(tp)
-
-	poly.Exterior = parts.Linearize(res)
-	poly.Exterior = RemoveDuplicates(poly.Exterior, func(p, q Point) bool { return p.Equals(q) })
-	fmt.Fprintf(os.Stderr, "area: %f\n", Ring(poly.Exterior).Area())
-	if area := Ring(poly.Exterior).Area(); area < 0 {
-		Reverse(poly.Exterior)
+	orient(p.Exterior, clockwise)
+	for _, h := range p.Holes {
+		orient(h, !clockwise)
 	}
-	tp = Map(poly.Exterior, func(p Point) triangolatte.Point {
-		return triangolatte.Point{X: p.X, Y: p.Y}
-	})
+	if !reversed {
+		return
+	}
+	// Steiner points don't move
+	for range p.Steiner {
+		perm = append(perm, len(perm))
+	}
+	for i, t := range p.Triangles {
+		p.Triangles[i] = t.Remap(perm)
+	}
+}
 
-	// for _, p := range poly.Exterior {
-	// 	tp = append(tp, triangolatte.Point{X: p.X, Y: p.Y})
-	// }
+// recordWinding saves the current winding of every ring, see ExteriorWasClockwise
+func (p *Polygon) recordWinding() {
+	p.ExteriorWasClockwise = Ring(p.Exterior).IsClockwise()
+	p.HolesWereClockwise = make([]bool, len(p.Holes))
+	for i, h := range p.Holes {
+		p.HolesWereClockwise[i] = Ring(h).IsClockwise()
+	}
+}
 
-	indices := make(map[triangolatte.Point]int)
-	for i := 0; i < len(tp); i++ {
-		indices[tp[i]] = i
+// Vertices returns the exterior, hole and Steiner points as the single list
+// that Triangles indexes
+func (p Polygon) Vertices() []Point {
+	if len(p.Holes) == 0 && len(p.Steiner) == 0 {
+		return p.Exterior
 	}
+	ret := append([]Point(nil), p.Exterior...)
+	for _, h := range p.Holes {
+		ret = append(ret, h...)
+	}
+	return append(ret, p.Steiner...)
+}
 
-	fmt.Fprintf(os.Stderr, "polys: %#v\n", poly)
+// TrianglesFrom returns the triangles with the vertices numbered from base
+// instead of 0, 1 gives the indices of an obj file holding only p
+func (p Polygon) TrianglesFrom(base int) []Triangle {
+	ret := make([]Triangle, len(p.Triangles))
+	for i, t := range p.Triangles {
+		ret[i] = t.Offset(base)
+	}
+	return ret
+}
 
-	tris, err := triangolatte.Polygon(tp)
-	if err != nil {
-		return nil, err
+// TrianglePoints returns the corners of every triangle, looked up in Vertices
+// so those on holes and Steiner points resolve too
+func (p Polygon) TrianglePoints() [][3]Point {
+	vertices := p.Vertices()
+	ret := make([][3]Point, len(p.Triangles))
+	for i, t := range p.Triangles {
+		ret[i] = [3]Point{vertices[t[0]], vertices[t[1]], vertices[t[2]]}
 	}
+	return ret
+}
 
-	fmt.Fprintf(os.Stderr, "tris: %#v\n", tris)
+// dedupeRing removes repeated consecutive vertices, including a last vertex
+// repeating the first
+func dedupeRing(r []Point) []Point {
+	r = RemoveDuplicates(r, func(p, q Point) bool { return p.Equals(q) })
+	if len(r) > 1 && r[0].Equals(r[len(r)-1]) {
+		r = r[:len(r)-1]
+	}
+	return r
+}
 
-	if el.Attributes["fill"] != "" {
-		poly.Fill = MustParseColor(el.Attributes["fill"])
+// RemoveDuplicates dedupes the vertices of each ring separately. Points
+// shared between rings, like a hole touching the exterior, are kept on both.
+func (p *Polygon) RemoveDuplicates() {
+	p.Exterior = dedupeRing(p.Exterior)
+	for i := range p.Holes {
+		p.Holes[i] = dedupeRing(p.Holes[i])
 	}
-	for i := 0; i < len(tris); i += 6 {
-		A := triangolatte.Point{X: tris[i+0], Y: tris[i+1]}
-		B := triangolatte.Point{X: tris[i+2], Y: tris[i+3]}
-		C := triangolatte.Point{X: tris[i+4], Y: tris[i+5]}
+}
 
-		poly.Triangles = append(poly.Triangles, [3]int{
-			indices[A], indices[B], indices[C],
-		})
+// linearizePathElement parses the d attribute of a path and flattens each of
+// its subpaths into a list of points
+func linearizePathElement(el *svgparser.Element, opts Options) (rings [][]Point, err error) {
+	if opts.Resolution <= 0 {
+		panic(fmt.Errorf("negative bezier increment"))
 	}
 
-	// fmt.Printf("d: %s\n", d)
+	d := el.Attributes["d"]
 
-	return &poly, nil
-}
+	fmt.Fprintf(os.Stderr, "d attribute: %s\n", d)
 
-func PolygonFromRectElement(el *svgparser.Element) (*Polygon, error) {
-	var poly Polygon
+	dreader := NewSVGDReader(strings.NewReader(d))
+	dreader.Strict = !opts.Lenient
 
-	var x0, y0, x1, y1 float64
-	var err error
-	if x0, err = strconv.ParseFloat(el.Attributes["x"], 64); err != nil {
-		return nil, err
-	}
-	if y0, err = strconv.ParseFloat(el.Attributes["y"], 64); err != nil {
-		return nil, err
+	start := time.Now()
+	parts, err := dreader.Parse()
+	if err != nil {
+		return
 	}
-	if x1, err = strconv.ParseFloat(el.Attributes["width"], 64); err != nil {
-		return nil, err
-	} else {
-		x1 += x0
+	if opts.Metrics != nil {
+		opts.Metrics.Parse += time.Since(start)
 	}
-	if y1, err = strconv.ParseFloat(el.Attributes["height"], 64); err != nil {
-		return nil, err
-	} else {
-		y1 += y0
+	if len(parts) > 0 {
+		if parts, err = startWithMove(parts, opts); err != nil {
+			return
+		}
 	}
 
-	poly.Exterior = []Point{
-		{X: x0, Y: y0},
-		{X: x0, Y: y1},
-		{X: x1, Y: y1},
-		{X: x1, Y: y0},
+	start = time.Now()
+	for _, r := range parts.linearizeRings(flattening{opts.Resolution, opts.MaxSegmentLength, opts.ArcsAsCubics}) {
+		rings = append(rings, RemoveDuplicates(r, func(p, q Point) bool { return p.Equals(q) }))
 	}
-	//TODO: check right handed/v/left handed
-	poly.Triangles = []Triangle{
-		{0, 1, 2},
-		{2, 3, 0},
+	if opts.Metrics != nil {
+		opts.Metrics.Linearize += time.Since(start)
 	}
-	if el.Attributes["fill"] != "" {
-		poly.Fill = MustParseColor(el.Attributes["fill"])
+	return
+}
+
+// startWithMove checks that a path begins with a moveto, when lenient a path
+// starting with any other command is moved to that command's first coordinate
+func startWithMove(parts SVGDParts, opts Options) (SVGDParts, error) {
+	var first Point
+	switch p := parts[0].(type) {
+	case SVGDAbsoluteMovePart, SVGDRelativeMovePart:
+		return parts, nil
+	case SVGDAbsoluteLinePart:
+		first = p.Point
+	case SVGDRelativeLinePart:
+		first = p.Point
+	case SVGDAbsoluteSmoothQuadraticPart:
+		first = p.Point
+	case SVGDRelativeSmoothQuadraticPart:
+		first = p.Point
+	case SVGDAbsoluteHorizontalPart:
+		first = Point{X: p.distance}
+	case SVGDRelativeHorizontalPart:
+		first = Point{X: p.distance}
+	case SVGDAbsoluteVerticalPart:
+		first = Point{Y: p.distance}
+	case SVGDRelativeVerticalPart:
+		first = Point{Y: p.distance}
+	case SVGDAbsoluteCurvePart:
+		first = p.points[0]
+	case SVGDRelativeCurvePart:
+		first = p.points[0]
+	case SVGDAbsoluteQuadraticPart:
+		first = p.points[0]
+	case SVGDRelativeQuadraticPart:
+		first = p.points[0]
+	case SVGDAbsoluteArcPart:
+		first = p.Point
+	case SVGDRelativeArcPart:
+		first = p.Point
+	}
+	if !opts.Lenient {
+		return nil, &ParseError{Offset: 0, Err: ErrMissingMoveto}
+	}
+	return append(SVGDParts{SVGDAbsoluteMovePart{Point: first}}, parts...), nil
+}
+
+// PolygonsFromPathElement converts a path to a polygon for each of its
+// subpaths that isn't a hole, with the holes inside it
+func PolygonsFromPathElement(el *svgparser.Element, opts Options) ([]Polygon, error) {
+	key := newPathCacheKey(el, opts)
+	r, ok := opts.PathCache.get(key)
+	if !ok {
+		// warnings and timings are recorded for a cache hit to report again
+		geomOpts := opts
+		geomOpts.Metrics = &r.metrics
+		geomOpts.Warn = func(msg string) { r.warnings = append(r.warnings, msg) }
+		var err error
+		if r.polys, err = pathGeometry(el, geomOpts); err != nil {
+			opts.report(r)
+			return nil, err
+		}
+		opts.PathCache.add(key, r)
+	}
+	opts.report(r)
+
+	polys := r.polys
+	if el.Attributes["fill"] != "" {
+		fill, err := PaintColor(el.Attributes["fill"], opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range polys {
+			polys[i].Fill = fill
+		}
+	}
+	return polys, nil
+}
+
+// report passes on the warnings and timings of converting a path
+func (o Options) report(r pathResult) {
+	for _, msg := range r.warnings {
+		o.warnf("%s", msg)
+	}
+	if o.Metrics != nil {
+		o.Metrics.add(&r.metrics)
+	}
+}
+
+// pathGeometry is the part of PolygonsFromPathElement that PathCache remembers
+func pathGeometry(el *svgparser.Element, opts Options) (polys []Polygon, err error) {
+	rings, err := linearizePathElement(el, opts)
+	if err != nil || len(rings) == 0 {
+		return
+	}
+	rule, err := fillRuleOf(el, opts)
+	if err != nil {
+		return
+	}
+	// nesting decides which subpaths are exteriors and holes, whatever order
+	// they are drawn in. Under nonzero a nested ring winding the same way as
+	// its exterior fills nothing more and isn't a hole. A subpath without
+	// area is left for Options.EmptyPolygons to deal with.
+	exteriors, holes := ClassifyRings(rings)
+	if len(exteriors) == 0 {
+		// the path has no area anywhere, its first subpath stands in for it
+		exteriors = rings[:1]
+	}
+	for k, e := range exteriors {
+		if Ring(e).Area() != 0 && !fillsInside(rings, e, rule) {
+			opts.warnf("%s has a subpath the fill-rule leaves empty, it is dropped", describeElement(el))
+			continue
+		}
+		poly := Polygon{Exterior: e}
+		for _, h := range holes[k] {
+			if !fillsInside(rings, rings[h], rule) {
+				poly.Holes = append(poly.Holes, rings[h])
+			}
+		}
+		polys = append(polys, poly)
+	}
+	for i := range polys {
+		if err = opts.finishPathPolygon(&polys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// finishPathPolygon cleans up, transforms and triangulates one polygon of a
+// path
+func (o Options) finishPathPolygon(poly *Polygon) (err error) {
+	poly.RemoveDuplicates()
+	poly.recordWinding()
+	// transform every ring before the winding is checked
+	poly.Transform(o.currentTransform())
+	o.quantize(poly)
+
+	fmt.Fprintf(os.Stderr, "area: %f\n", Ring(poly.Exterior).Area())
+	poly.NormalizeWinding(false)
+
+	if o.Triangulate {
+		start := time.Now()
+		if err = o.triangulate(poly); err != nil {
+			return
+		}
+		if o.MeshQuality > 0 {
+			RefineTriangulation(poly, o.MeshQuality)
+		}
+		if o.Metrics != nil {
+			o.Metrics.Triangulate += time.Since(start)
+		}
+	}
+	return
+}
+
+func PolygonFromRectElement(el *svgparser.Element, opts Options) (*Polygon, error) {
+	var poly Polygon
+
+	// missing attributes are zero, and a rect without a size isn't drawn
+	var x0, y0, x1, y1 float64
+	var err error
+	if x0, err = floatAttr(el, "x", 0); err != nil {
+		return nil, err
+	}
+	if y0, err = floatAttr(el, "y", 0); err != nil {
+		return nil, err
+	}
+	if x1, err = floatAttr(el, "width", 0); err != nil {
+		return nil, err
+	} else if x1 <= 0 {
+		return nil, nil
+	} else {
+		x1 += x0
+	}
+	if y1, err = floatAttr(el, "height", 0); err != nil {
+		return nil, err
+	} else if y1 <= 0 {
+		return nil, nil
+	} else {
+		y1 += y0
+	}
+
+	poly.Exterior = []Point{
+		{X: x0, Y: y0},
+		{X: x0, Y: y1},
+		{X: x1, Y: y1},
+		{X: x1, Y: y0},
+	}
+	poly.Transform(opts.currentTransform())
+	opts.quantize(&poly)
+	//TODO: check right handed/v/left handed
+	if opts.Triangulate {
+		poly.Triangles = []Triangle{
+			{0, 1, 2},
+			{2, 3, 0},
+		}
+	}
+	if el.Attributes["fill"] != "" {
+		if poly.Fill, err = PaintColor(el.Attributes["fill"], opts); err != nil {
+			return nil, err
+		}
 	}
 
 	return &poly, nil
 }
 
-func PolygonFromPolygonElement(el *svgparser.Element) (*Polygon, error) {
-	var poly []triangolatte.Point
-	coords := coordsSplitter.Split(el.Attributes["points"], -1)
-	var ret Polygon
+// parsePoints reads the coordinate pairs of a points attribute
+func parsePoints(points string) (ret []Point, err error) {
+	// splitting would leave empty tokens around leading or trailing whitespace
+	coords := strings.FieldsFunc(points, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
 
 	// fmt.Printf("coords: %v", coords)
 	fmt.Fprintf(os.Stderr, "coords: %v\n", coords)
@@ -628,113 +1338,809 @@ func PolygonFromPolygonElement(el *svgparser.Element) (*Polygon, error) {
 			return nil, err
 		} else {
 			// indicies are the same
-			ret.Exterior = append(ret.Exterior, Point{X: x, Y: y})
+			ret = append(ret, Point{X: x, Y: y})
 		}
 	}
+	return
+}
 
-	if area := Ring(ret.Exterior).Area(); area < 0 {
-		Reverse(ret.Exterior)
+// PolygonFromPolylineElement fills a polyline, which is filled as if it were
+// closed
+func PolygonFromPolylineElement(el *svgparser.Element, opts Options) (*Polygon, error) {
+	return PolygonFromPolygonElement(el, opts)
+}
+
+func PolygonFromPolygonElement(el *svgparser.Element, opts Options) (*Polygon, error) {
+	var ret Polygon
+	var err error
+
+	start := time.Now()
+	if ret.Exterior, err = parsePoints(el.Attributes["points"]); err != nil {
+		return nil, err
 	}
+	if opts.Metrics != nil {
+		opts.Metrics.Parse += time.Since(start)
+	}
+	ret.recordWinding()
+	ret.Transform(opts.currentTransform())
+	opts.quantize(&ret)
+
+	ret.NormalizeWinding(false)
 	fmt.Fprintf(os.Stderr, "area: %f\n", Ring(ret.Exterior).Area())
 
-	poly = Map(ret.Exterior, func(p Point) triangolatte.Point {
-		return triangolatte.Point{X: p.X, Y: p.Y}
-	})
+	if opts.Triangulate {
+		start = time.Now()
+		if err := opts.triangulate(&ret); err != nil {
+			return nil, err
+		}
+		if opts.MeshQuality > 0 {
+			RefineTriangulation(&ret, opts.MeshQuality)
+		}
+		if opts.Metrics != nil {
+			opts.Metrics.Triangulate += time.Since(start)
+		}
+	}
 
+	if el.Attributes["fill"] != "" {
+		if ret.Fill, err = PaintColor(el.Attributes["fill"], opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ret, nil
+}
+
+// Triangulate fills in the triangles of poly from its exterior and holes. The
+// rings are cleaned in place first, collinear vertices and holes left without
+// area are dropped, then handed to the triangulator in the winding it expects.
+func Triangulate(poly *Polygon) error {
+	toTriangolatte := func(ring []Point, ccw bool) []triangolatte.Point {
+		tp := Map(ring, func(p Point) triangolatte.Point {
+			return triangolatte.Point{X: p.X, Y: p.Y}
+		})
+		if Ring(ring).IsClockwise() == ccw {
+			Reverse(tp)
+		}
+		return tp
+	}
+
+	// any earlier refinement no longer applies
+	poly.Steiner = nil
+	poly.Triangles = make([]Triangle, 0, len(poly.Triangles))
+
+	// collinear runs trip up the ear clipper and add nothing
+	poly.Exterior = removeCollinear(poly.Exterior)
+	holes := make([][]Point, 0, len(poly.Holes))
+	wasClockwise := make([]bool, 0, len(poly.HolesWereClockwise))
+	for i, h := range poly.Holes {
+		if h = removeCollinear(h); len(h) > 2 {
+			holes = append(holes, h)
+			if i < len(poly.HolesWereClockwise) {
+				wasClockwise = append(wasClockwise, poly.HolesWereClockwise[i])
+			}
+		}
+	}
+	poly.Holes, poly.HolesWereClockwise = holes, wasClockwise
+
+	// lines and points have nothing to triangulate
+	distinct := make(map[Point]bool)
+	for _, p := range poly.Exterior {
+		distinct[p] = true
+	}
+	if len(distinct) < 3 {
+		return nil
+	}
+
+	// where a point appears more than once the first, exterior most, index is
+	// used
 	indices := make(map[triangolatte.Point]int)
-	for i := 0; i < len(poly); i++ {
-		indices[poly[i]] = i
+	for i, p := range poly.Vertices() {
+		tp := triangolatte.Point{X: p.X, Y: p.Y}
+		if _, ok := indices[tp]; !ok {
+			indices[tp] = i
+		}
 	}
 
-	// Reverse(poly)
+	tp := toTriangolatte(poly.Exterior, true)
+	if len(poly.Holes) > 0 {
+		rings := [][]triangolatte.Point{tp}
+		for _, h := range poly.Holes {
+			// a hole pinching the outline is joined at the vertex they share,
+			// a bridge from elsewhere would cross the pinch
+			ring := toTriangolatte(h, false)
+			if outline, ok := spliceHole(rings[0], ring); ok {
+				rings[0] = outline
+			} else {
+				rings = append(rings, ring)
+			}
+		}
+		var err error
+		if tp, err = triangolatte.JoinHoles(rings); err != nil {
+			return err
+		}
+	}
 
-	tris, err := triangolatte.Polygon(poly)
+	tris, err := triangolatte.Polygon(tp)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if el.Attributes["fill"] != "" {
-		ret.Fill = MustParseColor(el.Attributes["fill"])
-	}
 	for i := 0; i < len(tris); i += 6 {
 		A := triangolatte.Point{X: tris[i+0], Y: tris[i+1]}
 		B := triangolatte.Point{X: tris[i+2], Y: tris[i+3]}
 		C := triangolatte.Point{X: tris[i+4], Y: tris[i+5]}
 
-		ret.Triangles = append(ret.Triangles, [3]int{
+		poly.Triangles = append(poly.Triangles, [3]int{
 			indices[A], indices[B], indices[C],
 		})
 	}
+	return nil
+}
 
-	return &ret, nil
+// spliceHole joins hole into outline at the first vertex they share, walking
+// around the hole and back to the shared vertex before carrying on along the
+// outline. ok is false when they share no vertex.
+func spliceHole(outline, hole []triangolatte.Point) (ret []triangolatte.Point, ok bool) {
+	for i, p := range outline {
+		j := slices.Index(hole, p)
+		if j < 0 {
+			continue
+		}
+		ret = make([]triangolatte.Point, 0, len(outline)+len(hole)+1)
+		ret = append(ret, outline[:i+1]...)
+		ret = append(ret, hole[j+1:]...)
+		ret = append(ret, hole[:j+1]...)
+		return append(ret, outline[i+1:]...), true
+	}
+	return outline, false
+}
+
+// triangulate is Triangulate, first merging vertices closer than
+// opts.SnapTolerance and moving the polygon to the origin when
+// opts.RecenterForTriangulation is set. Every vertex is put back exactly as
+// it was, only the arithmetic of the triangulator sees the shifted values.
+func (o Options) triangulate(poly *Polygon) error {
+	if o.SnapTolerance > 0 {
+		mergeVertices(poly, o.SnapTolerance)
+	}
+	if !o.RecenterForTriangulation {
+		return Triangulate(poly)
+	}
+	min := poly.Bounds().Min
+	original := make(map[Point]Point)
+	// shifted copies so nothing sharing the rings sees the change
+	shift := func(ring []Point) []Point {
+		ret := make([]Point, len(ring))
+		for i, p := range ring {
+			ret[i] = Point{X: p.X - min.X, Y: p.Y - min.Y}
+			original[ret[i]] = p
+		}
+		return ret
+	}
+	restore := func(ring []Point) {
+		for i, p := range ring {
+			if q, ok := original[p]; ok {
+				ring[i] = q
+			} else {
+				ring[i] = p.Add(min)
+			}
+		}
+	}
+
+	poly.Exterior = shift(poly.Exterior)
+	holes := make([][]Point, len(poly.Holes))
+	for i, h := range poly.Holes {
+		holes[i] = shift(h)
+	}
+	poly.Holes = holes
+	err := Triangulate(poly)
+	restore(poly.Exterior)
+	for _, h := range poly.Holes {
+		restore(h)
+	}
+	return err
 }
 
-func ExtractPolygons(el *svgparser.Element) (ret []Polygon, err error) {
-	var stack []*svgparser.Element
+// splitTouching is Polygon.SplitTouching with each piece wound and
+// triangulated like any other polygon
+func (o Options) splitTouching(p Polygon) ([]Polygon, error) {
+	pieces := p.SplitTouching()
+	for i := range pieces {
+		pieces[i].NormalizeWinding(false)
+		if !o.Triangulate {
+			continue
+		}
+		if err := o.triangulate(&pieces[i]); err != nil {
+			return nil, err
+		}
+		if o.MeshQuality > 0 {
+			RefineTriangulation(&pieces[i], o.MeshQuality)
+		}
+	}
+	return pieces, nil
+}
+
+// mergeVertices moves every vertex within tolerance of one seen before it onto
+// that vertex, then drops the repeats this leaves in each ring. Vertices are
+// bucketed in a grid of tolerance sized cells so only neighbouring cells are
+// searched.
+func mergeVertices(poly *Polygon, tolerance float64) {
+	type cell struct{ x, y int64 }
+	cells := make(map[cell][]Point)
+	merge := func(ring []Point) {
+		for i, p := range ring {
+			c := cell{int64(math.Floor(p.X / tolerance)), int64(math.Floor(p.Y / tolerance))}
+			found := false
+			for dx := int64(-1); dx <= 1 && !found; dx++ {
+				for dy := int64(-1); dy <= 1 && !found; dy++ {
+					for _, q := range cells[cell{c.x + dx, c.y + dy}] {
+						if math.Hypot(p.X-q.X, p.Y-q.Y) <= tolerance {
+							ring[i], found = q, true
+							break
+						}
+					}
+				}
+			}
+			if !found {
+				cells[c] = append(cells[c], p)
+			}
+		}
+	}
+	merge(poly.Exterior)
+	for _, h := range poly.Holes {
+		merge(h)
+	}
+	poly.RemoveDuplicates()
+}
+
+// removeCollinear returns a copy of ring without the vertices lying on the
+// line through their neighbours
+func removeCollinear(ring []Point) []Point {
+	r := append([]Point(nil), ring...)
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(r) && len(r) > 2; i++ {
+			o, a, b := Ring(r).At(i-1), r[i], Ring(r).At(i+1)
+			if math.Abs(cross(o, a, b)) <= 1e-9*math.Hypot(a.X-o.X, a.Y-o.Y)*math.Hypot(b.X-o.X, b.Y-o.Y) {
+				r = append(r[:i], r[i+1:]...)
+				i--
+				changed = true
+			}
+		}
+	}
+	return r
+}
+
+// RetriangulatePolygon recomputes the triangles of a single polygon after its
+// rings have been edited, Steiner points from any refinement are dropped
+func RetriangulatePolygon(p *Polygon) error {
+	p.RemoveDuplicates()
+	p.NormalizeWinding(false)
+	return Triangulate(p)
+}
+
+// labelOf is the accessible name of el, its aria-label or else the text of
+// its <title> child
+func labelOf(el *svgparser.Element) string {
+	if label := strings.TrimSpace(el.Attributes["aria-label"]); label != "" {
+		return label
+	}
+	for _, child := range el.Children {
+		if child.Name == "title" {
+			return strings.Join(strings.Fields(child.Content), " ")
+		}
+	}
+	return ""
+}
+
+// describeElement names an element for error and warning messages
+func describeElement(el *svgparser.Element) string {
+	if id := el.Attributes["id"]; id != "" {
+		return fmt.Sprintf("<%s id=\"%s\">", el.Name, id)
+	}
+	return fmt.Sprintf("<%s>", el.Name)
+}
+
+// ExtractPolygons converts every shape under el, moving the result to the
+// origin given by opts.Recenter once all of them are known
+func ExtractPolygons(el *svgparser.Element, opts Options) (ret []Polygon, err error) {
+	err = WalkPolygons(el, opts, func(p Polygon) error {
+		ret = append(ret, p)
+		return nil
+	})
+	RecenterPolygons(ret, opts.Recenter)
+	return
+}
+
+// WalkPolygons converts the shapes under el, calling fn with each polygon as
+// soon as it is produced. Walking stops at the first error from fn.
+// With opts.SkipInvalid an element that fails to convert is left out instead
+// of stopping the walk, and the failures are returned as ElementErrors.
+func WalkPolygons(el *svgparser.Element, opts Options, fn func(Polygon) error) (err error) {
+	type frame struct {
+		el *svgparser.Element
+		// transform of the parent
+		m Matrix
+		// inherited visibility:hidden, which children can override
+		hidden bool
+		// size of the nearest svg viewport in its own user units
+		viewport [2]float64
+		// how far below the first element, counting each use as a level
+		depth int
+		// the use elements being expanded, one seen again is a cycle
+		uses []*svgparser.Element
+	}
+	var stack []frame
+	var failed ElementErrors
+	// with SkipInvalid a failing element is recorded instead of stopping the
+	// walk, the caller then skips it. Going over a limit, like in a marker,
+	// always stops it.
+	fail := func(el *svgparser.Element, err error) error {
+		err = fmt.Errorf("%s: %w", describeElement(el), err)
+		var limit *LimitError
+		if !opts.SkipInvalid || errors.As(err, &limit) {
+			return err
+		}
+		failed = append(failed, err)
+		return nil
+	}
 
-	stack = append(stack, el)
+	root := el
+	base := opts.currentTransform()
+	if opts.Millimeters && root.Name == "svg" {
+		mm, err := millimeterTransform(root)
+		if err != nil {
+			return fmt.Errorf("%s: %v", describeElement(root), err)
+		}
+		base = base.Multiply(mm)
+	}
+	if opts.Scale != 0 && root.Name == "svg" {
+		base = Scale(opts.Scale, opts.Scale).Multiply(base)
+	}
+	stack = append(stack, frame{el, base, false, opts.viewport, 0, nil})
+	if opts.ids == nil {
+		opts.ids = indexIDs(root)
+	}
+	if opts.limits == nil {
+		opts.limits = &limits{}
+	}
+	baseOpts := opts
 
 	for len(stack) > 0 {
-		el, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		var f frame
+		f, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		el = f.el
+		if opts.limits.elements++; opts.MaxElements > 0 && opts.limits.elements > opts.MaxElements {
+			return &LimitError{What: "elements", Max: int64(opts.MaxElements)}
+		} else if opts.MaxDepth > 0 && f.depth > opts.MaxDepth {
+			return &LimitError{What: "levels of nesting", Max: int64(opts.MaxDepth)}
+		}
+
+		// nothing under display:none is drawn, hidden elements still have
+		// children that can be made visible again
+		if presentation(el, "display") == "none" {
+			continue
+		}
+		hidden := f.hidden
+		switch presentation(el, "visibility") {
+		case "hidden", "collapse":
+			hidden = true
+		case "visible":
+			hidden = false
+		}
+
+		m := f.m
+		t := el.Attributes["transform"]
+		if s := styleValue(el, "transform"); s != "" {
+			// css takes precedence over the attribute
+			t = s
+		}
+		if t != "" {
+			local, err := ParseTransform(t)
+			if err != nil {
+				if err = fail(el, err); err != nil {
+					return err
+				}
+				continue
+			}
+			m = m.Multiply(local)
+		}
+		// a nested viewport is placed inside its own transform
+		if el.Name == "svg" && el != root {
+			vm, err := viewportTransform(el)
+			if err != nil {
+				if err = fail(el, err); err != nil {
+					return err
+				}
+				continue
+			}
+			m = m.Multiply(vm)
+		}
+		viewport := f.viewport
+		if el.Name == "svg" {
+			if viewport, err = viewportSize(el); err != nil {
+				if err = fail(el, err); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		opts := baseOpts
+		opts.transform = &m
+		opts.viewport = viewport
 
+		// these contents are only drawn where they are referenced
 		switch el.Name {
-		case "polygon":
-			if poly, err := PolygonFromPolygonElement(el); err != nil {
-				return ret, err
+		case "marker", "pattern", "symbol", "defs":
+			continue
+		case "use":
+			id := strings.TrimPrefix(el.Attributes["href"], "#")
+			target := opts.ids[id]
+			if target == nil {
+				opts.warnf("%s references unknown element '%s'", describeElement(el), id)
+				continue
+			}
+			if slices.Index(f.uses, el) >= 0 {
+				if err = fail(el, fmt.Errorf("references '%s' recursively", id)); err != nil {
+					return err
+				}
+				continue
+			}
+			um, err := useTransform(el, target, m)
+			if err != nil {
+				if err = fail(el, err); err != nil {
+					return err
+				}
+				continue
+			}
+			uses := append(append([]*svgparser.Element(nil), f.uses...), el)
+			if target.Name == "symbol" {
+				for _, child := range target.Children {
+					stack = append(stack, frame{child, um, hidden, viewport, f.depth + 1, uses})
+				}
 			} else {
-				ret = append(ret, *poly)
+				stack = append(stack, frame{target, um, hidden, viewport, f.depth + 1, uses})
+			}
+			continue
+		}
+		if !containerElements[el.Name] && !shapeElements[el.Name] {
+			if !nonRenderingElements[el.Name] {
+				opts.skip(el)
+				if opts.OnElement != nil {
+					opts.OnElement(el.Name, nil)
+				}
+			}
+			continue
+		}
+
+		//TODO: apply clip paths, at least rectangular ones
+		for _, attr := range []string{"clip-path", "mask"} {
+			if v := el.Attributes[attr]; v != "" && v != "none" {
+				opts.warnf("%s has %s '%s' which is ignored, output is not clipped", describeElement(el), attr, v)
+			}
+		}
+
+		var polys []Polygon
+		// drawn over the fill and stroke, already counted against the limits
+		// by their own extraction
+		var markers []Polygon
+		var poly *Polygon
+		// unfilled shapes contribute no fill geometry of their own
+		filled := el.Attributes["fill"] != "none" && !hidden
+		// shapes that may touch themselves are triangulated once split
+		fillOpts := opts
+		if opts.SplitTouching {
+			fillOpts.Triangulate = false
+		}
+		// further pieces of the fill, from other subpaths of a path or split
+		// off where poly touches itself
+		var lobes []Polygon
+		switch el.Name {
+		case "polygon":
+			if filled {
+				poly, err = PolygonFromPolygonElement(el, fillOpts)
+			}
+		case "polyline":
+			if filled {
+				poly, err = PolygonFromPolylineElement(el, fillOpts)
 			}
 		case "rect":
-			if poly, err := PolygonFromRectElement(el); err != nil {
-				return ret, err
-			} else {
-				ret = append(ret, *poly)
+			if filled {
+				poly, err = PolygonFromRectElement(el, opts)
 			}
 		case "path":
-			if poly, err := PolygonFromPathElement(el, 0.1); err != nil {
-				return ret, err
-			} else {
-				ret = append(ret, *poly)
+			if filled {
+				var pieces []Polygon
+				if pieces, err = PolygonsFromPathElement(el, fillOpts); len(pieces) > 0 {
+					poly, lobes = &pieces[0], pieces[1:]
+				}
+			}
+			if err == nil && !hidden && el.Attributes["marker-end"] != "" {
+				// the marker goes at the end of the last subpath
+				var rings [][]Point
+				if rings, err = linearizePathElement(el, opts); err == nil && len(rings) > 0 {
+					markers, err = MarkerEndPolygons(root, el, rings[len(rings)-1], opts)
+				}
+			}
+		}
+		if err == nil && poly != nil && opts.SplitTouching && el.Name != "rect" {
+			var pieces []Polygon
+			for _, p := range append([]Polygon{*poly}, lobes...) {
+				var split []Polygon
+				if split, err = opts.splitTouching(p); err != nil {
+					break
+				}
+				pieces = append(pieces, split...)
+			}
+			if poly, lobes = nil, nil; len(pieces) > 0 {
+				poly, lobes = &pieces[0], pieces[1:]
+			}
+		}
+		if err == nil && opts.Strokes && !hidden {
+			var stroke *Polygon
+			if stroke, err = StrokeFromShapeElement(el, opts); stroke != nil {
+				polys = append(polys, *stroke)
+			}
+		}
+		if err != nil {
+			if err = fail(el, err); err != nil {
+				return err
+			}
+			if opts.OnElement != nil {
+				opts.OnElement(el.Name, nil)
+			}
+			continue
+		}
+		if poly != nil {
+			var opacity float64
+			if opacity, err = opacityOf(el, "fill-opacity"); err != nil {
+				return err
+			}
+			poly.Fill.A *= opacity
+			poly.ID = el.Attributes["id"]
+			poly.Label = labelOf(el)
+			for i := range lobes {
+				lobes[i].Fill, lobes[i].ID, lobes[i].Label = poly.Fill, poly.ID, poly.Label
+			}
+			shade := func(p *Polygon) (err error) {
+				if p.PerVertexColors, err = gradientColors(el, *p, m, opts); err != nil {
+					return
+				}
+				for _, colors := range p.PerVertexColors {
+					for i := range colors {
+						colors[i].A *= opacity
+					}
+				}
+				return
+			}
+			if err = shade(poly); err != nil {
+				return err
+			}
+			for i := range lobes {
+				if err = shade(&lobes[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if poly != nil {
+			// poly is the first piece left once empty ones are dropped
+			pieces := append([]Polygon{*poly}, lobes...)
+			poly, lobes = nil, nil
+			for i := range pieces {
+				if opts.isEmpty(pieces[i]) {
+					switch opts.EmptyPolygons {
+					case KeepEmpty:
+					case ErrorEmpty:
+						return fmt.Errorf("%s has no area", describeElement(el))
+					default:
+						continue
+					}
+				}
+				if poly == nil {
+					poly = &pieces[i]
+				} else {
+					lobes = append(lobes, pieces[i])
+				}
+			}
+		}
+		if opts.OnElement != nil && shapeElements[el.Name] {
+			opts.OnElement(el.Name, poly)
+		}
+		if poly != nil {
+			polys = append(append([]Polygon{*poly}, lobes...), polys...)
+		}
+		for _, p := range polys {
+			if opts.limits.vertices += len(p.Vertices()); opts.MaxVertices > 0 && opts.limits.vertices > opts.MaxVertices {
+				return &LimitError{What: "output vertices", Max: int64(opts.MaxVertices)}
+			}
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+		for _, p := range markers {
+			if err := fn(p); err != nil {
+				return err
 			}
 		}
 
-		stack = append(stack, el.Children...)
+		// marker polygons are counted by their own extraction
+		if opts.Metrics != nil && poly != nil {
+			opts.Metrics.Elements++
+			opts.Metrics.Polygons++
+			opts.Metrics.Vertices += len(poly.Vertices())
+			opts.Metrics.Triangles += len(poly.Triangles)
+		}
+
+		if containerElements[el.Name] {
+			for _, child := range el.Children {
+				stack = append(stack, frame{child, m, hidden, viewport, f.depth + 1, f.uses})
+			}
+		}
 	}
-	return
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
 }
 
-func WriteOBJ(writer io.Writer, polys []Polygon) {
-	firstVertex := make(map[int]int)
-	count := 1
-	for i, p := range polys {
-		firstVertex[i] = count
-		count += len(p.Exterior)
+func ParseDocument(r io.Reader, opts Options) (*svgparser.Element, error) {
+	start := time.Now()
+	if opts.MaxBytes > 0 {
+		r = &limitReader{r: r, left: opts.MaxBytes, max: opts.MaxBytes}
+	}
+	elements, err := svgparser.Parse(r, false)
+	if err != nil {
+		return nil, err
+	}
+	applyStylesheets(elements, opts)
+	if opts.Metrics != nil {
+		opts.Metrics.Parse += time.Since(start)
+	}
+	return elements, nil
+}
+
+// limitReader fails with a LimitError once more than max bytes are read,
+// where io.LimitReader would quietly cut the document short
+type limitReader struct {
+	r         io.Reader
+	left, max int64
+}
 
-		for _, v := range p.Exterior {
-			fmt.Fprintf(writer, "v %f %f 0\n", v.X, v.Y)
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.left < 0 {
+		return 0, &LimitError{What: "bytes", Max: l.max}
+	}
+	// read one byte past the limit to tell a document of exactly max bytes
+	// from a longer one
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	if l.left -= int64(n); l.left < 0 {
+		return 0, &LimitError{What: "bytes", Max: l.max}
+	}
+	return n, err
+}
+
+// Convert parses an SVG document from r and extracts its polygons
+func Convert(r io.Reader, opts Options) ([]Polygon, error) {
+	elements, err := ParseDocument(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractPolygons(elements, opts)
+}
+
+func WriteOBJ(writer io.Writer, polys []Polygon, opts Options) error {
+	w := NewOBJWriter(writer, opts)
+	for _, p := range polys {
+		if err := w.WritePolygon(p); err != nil {
+			return err
 		}
 	}
+	return w.Close()
+}
 
-	// fmt.Print("f ")
-	// v := 1
-	// for _, p := range polys {
-	// 	for _ = range p.Exterior {
-	// 		fmt.Printf("%d ", v)
-	// 		v++
-	// 	}
-	// }
-	// fmt.Print("\n")
+// WriteOBJMaterials writes polys as objects named after their fills, see
+// NewOBJMaterialWriter
+func WriteOBJMaterials(writer, mtl io.Writer, mtllib string, polys []Polygon, opts Options) error {
+	w := NewOBJMaterialWriter(writer, mtl, mtllib, opts)
+	for _, p := range polys {
+		if err := w.WritePolygon(p); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
 
-	for i, p := range polys {
-		f := firstVertex[i]
-		for _, t := range p.Triangles {
-			fmt.Fprintf(writer, "f %d %d %d\n", f+t[0], f+t[1], f+t[2])
+// gzipFile closes both the decompressor and the file under it
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// how long to wait on an svg given as a url
+const fetchTimeout = 30 * time.Second
+
+// OpenInput opens a local file or fetches an http(s) url
+func OpenInput(path string, opts Options) (io.ReadCloser, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		f, err := os.Open(path)
+		if err != nil || !strings.EqualFold(filepath.Ext(path), ".svgz") {
+			return f, err
+		}
+		z, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
 		}
+		return gzipFile{z, f}, nil
 	}
 
+	client := http.Client{Timeout: fetchTimeout}
+	res, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("fetching '%s': %s", path, res.Status)
+	}
+
+	// servers are often sloppy with svg content types so only warn
+	if ct, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err == nil {
+		switch ct {
+		case "image/svg+xml", "application/xml", "text/xml", "text/plain", "application/octet-stream":
+		default:
+			opts.warnf("'%s' has content type '%s', expected image/svg+xml", path, ct)
+		}
+	}
+	return res.Body, nil
 }
 
 func main() {
+	metrics := flag.Bool("metrics", false, "print per-phase timings and counts to stderr")
+	format := flag.String("format", "json", "output format: json, obj, stl, stlb (binary stl), ply, gltf, webgl, webgl64 (base64 buffers) or bin (see ReadBinary)")
+	strokes := flag.Bool("strokes", false, "emit stroke outlines as polygons")
+	z := flag.Float64("z", 0, "depth of every vertex in obj, stl, ply, gltf and webgl output")
+	triangulate := flag.Bool("triangulate", true, "triangulate polygons, when false only the cleaned rings are output")
+	lineJoin := flag.String("stroke-linejoin", "", "override stroke-linejoin: miter, round or bevel")
+	miterLimit := flag.Float64("stroke-miterlimit", 0, "override stroke-miterlimit")
+	fillRule := flag.String("fill-rule", "", "fill-rule for elements that don't set one: nonzero or evenodd")
+	floatColors := flag.Bool("float-colors", false, "write json fills as r, g, b and a components instead of hex")
+	strict := flag.Bool("strict", false, "fail on malformed input that would otherwise be accepted")
+	empty := flag.String("empty", "drop", "shapes with no area: drop, keep or error")
+	quality := flag.Float64("quality", 0, "refine triangles to this minimum angle in degrees, adding vertices")
+	mm := flag.Bool("mm", false, "output millimeters using the width, height and viewBox of the document")
+	preview := flag.String("preview", "", "also render the triangles to this png file")
+	previewSize := flag.Int("preview-size", 512, "size in pixels of the longer side of the preview")
+	outdir := flag.String("outdir", "", "when the input is a directory, where the converted files are written, the input directory when empty")
+	workers := flag.Int("workers", runtime.NumCPU(), "when the input is a directory, how many files are converted at once")
+	union := flag.Bool("union", false, "merge every shape into one outline per connected region, filled like the first shape")
+	uv := flag.String("uv", "", "texture coordinates in obj and gltf output spanning each polygon or the whole scene: polygon or scene")
+	weld := flag.Bool("weld", false, "merge coincident vertices of each polygon in indexed output formats")
+	doubleSided := flag.Bool("double-sided", false, "write every triangle of obj, stl, ply, gltf and webgl output twice, once in each winding")
+	mtl := flag.String("mtl", "", "with obj output, write materials for the fills to this file and name each object after its fill")
+	bboxOnly := flag.Bool("bbox-only", false, "write a json array of the id, fill and bounding box of every shape instead of its geometry")
+	symbols := flag.Bool("symbols", false, "write a json object of the polygons of every symbol by id instead of the drawing")
+	precision := flag.Int("precision", -1, "round vertices to this many decimal places, negative keeps full precision")
+	maxElements := flag.Int("max-elements", 0, "fail on documents with more elements than this, 0 is unlimited")
+	maxBytes := flag.Int64("max-bytes", 0, "fail on documents larger than this many bytes once decompressed, 0 is unlimited")
+	maxDepth := flag.Int("max-depth", 0, "fail on documents nesting elements, or uses of them, deeper than this, 0 is unlimited")
+	maxVertices := flag.Int("max-vertices", 0, "fail on documents converting to more vertices than this, 0 is unlimited")
+	scale := flag.Float64("scale", 1, "multiply every output coordinate by this, before any recentering")
+	recenterOutput := flag.String("recenter", "none", "move the output so the origin is at the center or the min corner of its bounding box: none, center or min")
+	splitTouching := flag.Bool("split-touching", false, "split shapes whose outline touches itself at a vertex, like a figure eight, into separate polygons")
+	arcCubics := flag.Bool("arc-cubics", false, "linearize arcs through cubic beziers of at most a quarter turn instead of sampling them directly")
+	maxSegment := flag.Float64("max-segment", 0, "split curves until no segment is longer than this many user units, 0 only uses the bezier step")
+	snap := flag.Float64("snap", 0, "merge vertices closer than this before triangulating")
+	recenter := flag.Bool("recenter-triangulation", false, "move each shape to the origin while triangulating, for very large coordinates")
+	keepGoing := flag.Bool("keep-going", false, "write every shape that converts, report the ones that fail and exit with status 1")
 	flag.Parse()
 	svgPath := ""
 
@@ -744,25 +2150,217 @@ func main() {
 		svgPath = flag.Arg(0)
 	}
 
-	country, err := os.Open(svgPath)
-	if err != nil {
-		panic(fmt.Errorf("error opening file: %v", err))
+	opts := DefaultOptions()
+	if *metrics {
+		opts.Metrics = &Metrics{}
 	}
-	elements, err := svgparser.Parse(country, false)
-	if err != nil {
-		panic(fmt.Errorf("error parsing svg '%s': %v", err, svgPath))
+	opts.Strokes = *strokes
+	opts.Triangulate = *triangulate
+	opts.Z = *z
+	opts.StrokeMiterLimit = *miterLimit
+	opts.MeshQuality = *quality
+	opts.Lenient = !*strict
+	opts.FloatColors = *floatColors
+	opts.Precision = *precision
+	opts.Millimeters = *mm
+	opts.Weld = *weld
+	opts.DoubleSided = *doubleSided
+	opts.SkipInvalid = *keepGoing
+	opts.RecenterForTriangulation = *recenter
+	opts.SnapTolerance = *snap
+	opts.MaxSegmentLength = *maxSegment
+	opts.ArcsAsCubics = *arcCubics
+	opts.SplitTouching = *splitTouching
+	opts.MaxElements = *maxElements
+	opts.MaxDepth = *maxDepth
+	opts.MaxVertices = *maxVertices
+	opts.Scale = *scale
+	opts.MaxBytes = *maxBytes
+	if rc, err := ParseRecenter(*recenterOutput); err != nil {
+		panic(err)
+	} else {
+		opts.Recenter = rc
+	}
+	switch *uv {
+	case "":
+	case "polygon", "scene":
+		opts.GenerateUVs = true
+	default:
+		panic(fmt.Errorf("unknown uv space '%s'", *uv))
+	}
+	if *fillRule != "" {
+		if rule, err := ParseFillRule(*fillRule); err != nil {
+			panic(err)
+		} else {
+			opts.FillRule = rule
+		}
+	}
+	if *lineJoin != "" {
+		if join, err := ParseLineJoin(*lineJoin); err != nil {
+			panic(err)
+		} else {
+			opts.StrokeLineJoin = join
+		}
+	}
+	if policy, err := ParseEmptyPolicy(*empty); err != nil {
+		panic(err)
+	} else {
+		opts.EmptyPolygons = policy
+	}
+
+	if info, err := os.Stat(svgPath); err == nil && info.IsDir() {
+		out := *outdir
+		if out == "" {
+			out = svgPath
+		}
+		results, err := ConvertDirectory(svgPath, out, *format, *workers, opts)
+		if err != nil {
+			panic(err)
+		}
+		failed := reportBatch(results)
+		if opts.Metrics != nil {
+			fmt.Fprintf(os.Stderr, "metrics: %v\n", opts.Metrics)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
 	}
 
-	polys, err := ExtractPolygons(elements)
+	country, err := OpenInput(svgPath, opts)
 	if err != nil {
-		panic(err)
+		panic(fmt.Errorf("error opening file: %v", err))
 	}
+	defer country.Close()
+
+	// polygons to render when a preview is asked for
+	var previewPolys []Polygon
+	// set when -keep-going left out elements that failed to convert
+	var partial bool
+	if *bboxOnly {
+		elements, err := ParseDocument(country, opts)
+		if err != nil {
+			panic(fmt.Errorf("error parsing svg '%s': %v", svgPath, err))
+		}
+		bounds, err := ExtractBounds(elements, opts)
+		if err != nil {
+			panic(fmt.Errorf("error converting svg '%s': %v", svgPath, err))
+		}
+		json.NewEncoder(os.Stdout).Encode(bounds)
+	} else if *symbols {
+		if *format != "json" {
+			panic(fmt.Errorf("-symbols only writes json"))
+		}
+		elements, err := ParseDocument(country, opts)
+		if err != nil {
+			panic(fmt.Errorf("error parsing svg '%s': %v", svgPath, err))
+		}
+		sheet, err := ExtractSymbols(elements, opts)
+		if err != nil {
+			panic(fmt.Errorf("error converting svg '%s': %v", svgPath, err))
+		}
+		out := make(map[string]interface{})
+		for id, polys := range sheet {
+			out[id] = Map(polys, func(p Polygon) interface{} { return jsonPolygon(p, opts) })
+		}
+		json.NewEncoder(os.Stdout).Encode(out)
+	} else {
+		elements, err := ParseDocument(country, opts)
+		if err != nil {
+			panic(fmt.Errorf("error parsing svg '%s': %v", svgPath, err))
+		}
+		// failed elements are reported once the rest has been written
+		var failed ElementErrors
+		walk := func(fn func(Polygon) error) error {
+			err := WalkPolygons(elements, opts, fn)
+			if f, ok := err.(ElementErrors); ok {
+				failed, err = f, nil
+			}
+			return err
+		}
+		// a union, recentering and scene texture coordinates need every
+		// polygon before any is written
+		if *uv == "scene" || *union || opts.Recenter != RecenterNone {
+			polys, err := ExtractPolygons(elements, opts)
+			if f, ok := err.(ElementErrors); ok {
+				failed = f
+			} else if err != nil {
+				panic(fmt.Errorf("error converting svg '%s': %v", svgPath, err))
+			}
+			if *union {
+				if polys, err = UnionPolygons(polys); err != nil {
+					panic(fmt.Errorf("error merging svg '%s': %v", svgPath, err))
+				}
+			}
+			if *uv == "scene" {
+				bounds := SceneBounds(polys)
+				opts.UVBounds = &bounds
+			}
+			walk = func(fn func(Polygon) error) error {
+				for _, p := range polys {
+					if err := fn(p); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
 
-	// WriteOBJ(os.Stdout, polys)
+		// json is streamed like every other format so output starts right
+		// away and memory doesn't grow with the document
+		writer, err := NewMeshWriter(*format, os.Stdout, opts)
+		if err != nil {
+			panic(err)
+		}
+		if *mtl != "" {
+			if *format != "obj" {
+				panic(fmt.Errorf("-mtl only applies to obj output"))
+			}
+			f, err := os.Create(*mtl)
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			writer = NewOBJMaterialWriter(os.Stdout, f, filepath.Base(*mtl), opts)
+		}
+		err = walk(func(p Polygon) error {
+			start := time.Now()
+			defer func() {
+				if opts.Metrics != nil {
+					opts.Metrics.Encode += time.Since(start)
+				}
+			}()
+			if *preview != "" {
+				previewPolys = append(previewPolys, p)
+			}
+			return writer.WritePolygon(p)
+		})
+		if err != nil {
+			panic(fmt.Errorf("error converting svg '%s': %v", svgPath, err))
+		} else if err := writer.Close(); err != nil {
+			panic(err)
+		}
+		for _, err := range failed {
+			fmt.Fprintf(os.Stderr, "error converting svg '%s': %v\n", svgPath, err)
+		}
+		partial = len(failed) > 0
+	}
 
-	encoder := json.NewEncoder(os.Stdout)
-	// encoder.SetIndent("", "\t")
-	encoder.Encode(polys)
+	if *preview != "" {
+		f, err := os.Create(*preview)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		if err := WritePreview(f, previewPolys, *previewSize); err != nil {
+			panic(err)
+		}
+	}
 
-	// fmt.Printf("tris: %v\n", polys)
+	if opts.Metrics != nil {
+		fmt.Fprintf(os.Stderr, "metrics: %v\n", opts.Metrics)
+	}
+	if partial {
+		os.Exit(1)
+	}
 }
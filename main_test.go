@@ -0,0 +1,888 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// convert extracts the polygons of an svg document held in a string
+func convert(t *testing.T, svg string, opts Options) []Polygon {
+	t.Helper()
+	doc, err := ParseDocument(strings.NewReader(svg), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	polys, err := ExtractPolygons(doc, opts)
+	if err != nil {
+		t.Fatalf("extracting polygons: %v", err)
+	}
+	return polys
+}
+
+func TestPointRound(t *testing.T) {
+	for _, c := range []struct {
+		p        Point
+		decimals int
+		want     Point
+	}{
+		{Point{X: 0.5, Y: -0.5}, 0, Point{X: 1, Y: -1}},
+		{Point{X: 1.25, Y: -1.25}, 1, Point{X: 1.3, Y: -1.3}},
+		{Point{X: -2.344, Y: -2.346}, 2, Point{X: -2.34, Y: -2.35}},
+		{Point{X: 1234.5, Y: -1250}, -2, Point{X: 1200, Y: -1300}},
+	} {
+		if got := c.p.Round(c.decimals); got != c.want {
+			t.Errorf("%v rounded to %d decimals is %v, want %v", c.p, c.decimals, got, c.want)
+		}
+	}
+	// negative values rounding to zero come out as +0
+	if got := (Point{X: -0.001, Y: -0.4}).Round(0); math.Signbit(got.X) || math.Signbit(got.Y) {
+		t.Errorf("got %v, want positive zeros", got)
+	}
+
+	for _, c := range []struct {
+		p, want Point
+	}{
+		{Point{X: 0.25, Y: -0.25}, Point{X: 0.5, Y: -0.5}},
+		{Point{X: 1.2, Y: -1.3}, Point{X: 1, Y: -1.5}},
+		{Point{X: -0.2, Y: 0.2}, Point{X: 0, Y: 0}},
+	} {
+		if got := c.p.Snap(0.5); got != c.want || math.Signbit(got.X) {
+			t.Errorf("%v snapped to 0.5 is %v, want %v", c.p, got, c.want)
+		}
+	}
+}
+
+func TestRingAtWraps(t *testing.T) {
+	r := Ring{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+	for i, want := range map[int]Point{-1: r[2], -3: r[0], -4: r[2], 3: r[0], 4: r[1]} {
+		if got := r.At(i); got != want {
+			t.Errorf("At(%d) is %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestReversePermutation(t *testing.T) {
+	ring := []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 1}}
+	original := append([]Point(nil), ring...)
+	tri := Triangle{0, 1, 2}
+
+	perm := ReversePermutation(ring)
+	for i, p := range original {
+		if ring[perm[i]] != p {
+			t.Errorf("vertex %d went to %d, which holds %v instead of %v", i, perm[i], ring[perm[i]], p)
+		}
+	}
+	r := tri.Remap(perm)
+	for k := range tri {
+		if ring[r[k]] != original[tri[k]] {
+			t.Errorf("remapped corner %d is %v, want %v", k, ring[r[k]], original[tri[k]])
+		}
+	}
+}
+
+func TestTriangulateDisabled(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Triangulate = false
+	polys := convert(t, `<svg><path d="M0 0 L10 0 L10 10 L0 10 Z" fill="#000000"/><rect x="20" width="5" height="5"/></svg>`, opts)
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want 2", len(polys))
+	}
+	for _, p := range polys {
+		if len(p.Triangles) != 0 || len(p.Exterior) != 4 {
+			t.Errorf("got %d triangles and exterior %v, want no triangles and 4 vertices", len(p.Triangles), p.Exterior)
+		}
+	}
+}
+
+func TestWindingRecorded(t *testing.T) {
+	ccw := convert(t, `<svg><path d="M0 0 L10 0 L10 10 L0 10 Z" fill="#000000"/></svg>`, DefaultOptions())
+	cw := convert(t, `<svg><path d="M0 0 L0 10 L10 10 L10 0 Z" fill="#000000"/></svg>`, DefaultOptions())
+	if len(ccw) != 1 || len(cw) != 1 {
+		t.Fatalf("got %d and %d polygons, want a square each", len(ccw), len(cw))
+	}
+	if ccw[0].ExteriorWasClockwise || !cw[0].ExteriorWasClockwise {
+		t.Errorf("got clockwise %v for the counter-clockwise square and %v for the clockwise one",
+			ccw[0].ExteriorWasClockwise, cw[0].ExteriorWasClockwise)
+	}
+	// the output is normalized whichever way the source went
+	if a, b := Ring(ccw[0].Exterior).IsClockwise(), Ring(cw[0].Exterior).IsClockwise(); a != b {
+		t.Errorf("output windings differ, clockwise %v and %v", a, b)
+	}
+}
+
+func TestEmptyPolygons(t *testing.T) {
+	const svg = `<svg><path d="M0 0 L10 0 L20 0 Z" fill="#000000"/><rect x="30" width="5" height="5"/></svg>`
+	opts := DefaultOptions()
+	if polys := convert(t, svg, opts); len(polys) != 1 || polys[0].Bounds().Min.X != 30 {
+		t.Errorf("got %v, want only the rect", polys)
+	}
+
+	opts.EmptyPolygons = KeepEmpty
+	if polys := convert(t, svg, opts); len(polys) != 2 {
+		t.Errorf("got %d polygons, want the degenerate path kept", len(polys))
+	}
+
+	opts.EmptyPolygons = ErrorEmpty
+	doc, err := ParseDocument(strings.NewReader(svg), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	if _, err := ExtractPolygons(doc, opts); err == nil {
+		t.Error("extracting a degenerate path succeeded, want an error")
+	}
+}
+
+func TestPointsWhitespace(t *testing.T) {
+	clean, err := parsePoints("0,0 10,0 10,10 0,10")
+	if err != nil {
+		t.Fatalf("parsing clean points: %v", err)
+	}
+	for _, s := range []string{
+		"\n\t0,0\t10,0\r\n10,10 \r\n 0,10\r\n",
+		"\r\n  0 , 0 ,\t10 0\n\n10\t10,,0 10 ",
+	} {
+		got, err := parsePoints(s)
+		if err != nil {
+			t.Errorf("parsing %q: %v", s, err)
+		} else if !reflect.DeepEqual(got, clean) {
+			t.Errorf("%q parsed as %v, want %v", s, got, clean)
+		}
+	}
+
+	polys := convert(t, "<svg><polygon points=\"\r\n\t0,0\t10,0\r\n10,10\r\n\t0,10\r\n\"/><polyline points=\"\n20,0\t30,0\r\n30,10\n\"/></svg>", DefaultOptions())
+	if len(polys) != 2 {
+		t.Errorf("got %d polygons, want the polygon and the polyline", len(polys))
+	}
+}
+
+func TestPathStartingWithLine(t *testing.T) {
+	const svg = `<svg><path d="L5 5 L15 5 L15 15 Z" fill="#000000"/></svg>`
+	opts := DefaultOptions()
+	opts.Lenient = true
+	polys := convert(t, svg, opts)
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want the triangle", len(polys))
+	}
+	if b := polys[0].Bounds(); b.Min != (Point{X: 5, Y: 5}) || len(polys[0].Exterior) != 3 {
+		t.Errorf("got exterior %v, want it to start at (5, 5) not the origin", polys[0].Exterior)
+	}
+
+	opts.Lenient = false
+	doc, err := ParseDocument(strings.NewReader(svg), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	if _, err := ExtractPolygons(doc, opts); !errors.Is(err, ErrMissingMoveto) {
+		t.Errorf("strict conversion fails with %v, want %v", err, ErrMissingMoveto)
+	}
+}
+
+func TestTwoPointPath(t *testing.T) {
+	const svg = `<svg><path d="M0 0 L10 10" fill="#000000"/></svg>`
+	if polys := convert(t, svg, DefaultOptions()); len(polys) != 0 {
+		t.Errorf("got %v, want the line dropped", polys)
+	}
+	opts := DefaultOptions()
+	opts.EmptyPolygons = KeepEmpty
+	polys := convert(t, svg, opts)
+	if len(polys) != 1 || len(polys[0].Exterior) != 2 || len(polys[0].Triangles) != 0 {
+		t.Errorf("got %v, want the two points kept without triangles", polys)
+	}
+}
+
+func TestRetriangulatePolygon(t *testing.T) {
+	polys := convert(t, `<svg><rect width="10" height="10"/><rect x="20" width="10" height="10"/></svg>`, DefaultOptions())
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want 2", len(polys))
+	}
+	neighbor := polys[1].Clone()
+
+	// bend the first edge out through a new vertex
+	p := &polys[0]
+	p.Exterior = append(p.Exterior, Point{})
+	copy(p.Exterior[2:], p.Exterior[1:])
+	p.Exterior[1] = Point{X: 5, Y: -5}
+	if err := RetriangulatePolygon(p); err != nil {
+		t.Fatalf("retriangulating: %v", err)
+	}
+	if len(p.Triangles) != 3 {
+		t.Errorf("got %d triangles, want 3 for five vertices", len(p.Triangles))
+	}
+	area := 0.
+	for _, tri := range p.TrianglePoints() {
+		area += math.Abs(Ring(tri[:]).Area()) / 2
+	}
+	if want := math.Abs(Ring(p.Exterior).Area()) / 2; math.Abs(area-want) > 1e-9 {
+		t.Errorf("triangles cover %g, want %g", area, want)
+	}
+	if !reflect.DeepEqual(polys[1], neighbor) {
+		t.Errorf("the neighbor changed to %v", polys[1])
+	}
+}
+
+func TestTriangulateLeavesCopies(t *testing.T) {
+	p := Polygon{Exterior: []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}}
+	if err := Triangulate(&p); err != nil {
+		t.Fatalf("triangulating: %v", err)
+	}
+	want := append([]Triangle(nil), p.Triangles...)
+
+	// a copy sharing the triangles is given a different outline
+	q := p
+	q.Exterior = []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 5, Y: 15}, {X: 0, Y: 10}}
+	if err := Triangulate(&q); err != nil {
+		t.Fatalf("triangulating the copy: %v", err)
+	}
+	if !reflect.DeepEqual(p.Triangles, want) {
+		t.Errorf("triangulating a copy changed the triangles to %v, want %v", p.Triangles, want)
+	}
+}
+
+func TestTriangulateCollinear(t *testing.T) {
+	// the first three vertices lie on one line
+	p := Polygon{Exterior: []Point{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}}
+	if err := Triangulate(&p); err != nil {
+		t.Fatalf("triangulating: %v", err)
+	}
+	if len(p.Exterior) != 4 || slices.Contains(p.Exterior, Point{X: 5, Y: 0}) {
+		t.Errorf("got exterior %v, want the middle of the collinear run dropped", p.Exterior)
+	}
+	if len(p.Triangles) != 2 {
+		t.Errorf("got %d triangles, want 2", len(p.Triangles))
+	}
+}
+
+func TestTriangulateKeepsSharedHoles(t *testing.T) {
+	p := Polygon{
+		Exterior: []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+		Holes: [][]Point{
+			// a hole without area, dropped when triangulating
+			{{X: 1, Y: 1}, {X: 2, Y: 1}, {X: 3, Y: 1}},
+			{{X: 4, Y: 4}, {X: 4, Y: 6}, {X: 6, Y: 6}, {X: 6, Y: 4}},
+		},
+		HolesWereClockwise: []bool{false, true},
+	}
+	holes := append([][]Point(nil), p.Holes...)
+	wasClockwise := append([]bool(nil), p.HolesWereClockwise...)
+
+	q := p
+	if err := Triangulate(&q); err != nil {
+		t.Fatalf("triangulating: %v", err)
+	}
+	if len(q.Holes) != 1 || len(q.HolesWereClockwise) != 1 || !q.HolesWereClockwise[0] {
+		t.Errorf("got holes %v wound %v, want only the square", q.Holes, q.HolesWereClockwise)
+	}
+	if !reflect.DeepEqual(p.Holes, holes) || !reflect.DeepEqual(p.HolesWereClockwise, wasClockwise) {
+		t.Errorf("triangulating a copy changed the holes to %v wound %v", p.Holes, p.HolesWereClockwise)
+	}
+}
+
+func TestHoleTouchingExterior(t *testing.T) {
+	polys := convert(t, `<svg><path d="M0 0 L10 0 L10 10 L0 10 L0 0 Z M0 0 L2 5 L5 2 Z" fill-rule="evenodd"/></svg>`, DefaultOptions())
+	if len(polys) != 1 || len(polys[0].Holes) != 1 {
+		t.Fatalf("got %v, want a square with a hole", polys)
+	}
+	p := polys[0]
+	if len(p.Exterior) != 4 || len(p.Holes[0]) != 3 {
+		t.Errorf("got exterior %v and hole %v, want 4 and 3 vertices", p.Exterior, p.Holes[0])
+	}
+	corner := Point{X: 0, Y: 0}
+	if !slices.Contains(p.Exterior, corner) || !slices.Contains(p.Holes[0], corner) {
+		t.Errorf("the shared corner was merged away, exterior %v hole %v", p.Exterior, p.Holes[0])
+	}
+	// the triangles cover the square without the hole
+	area := 0.
+	vs := p.Vertices()
+	for _, tri := range p.Triangles {
+		// Area is twice the signed area
+		area += math.Abs(Ring{vs[tri[0]], vs[tri[1]], vs[tri[2]]}.Area()) / 2
+	}
+	if want := 100 - math.Abs(Ring(p.Holes[0]).Area())/2; math.Abs(area-want) > 1e-9 {
+		t.Errorf("triangles cover %g, want %g", area, want)
+	}
+}
+
+func TestQuadraticToCubic(t *testing.T) {
+	q := QuadraticBezier{p0: Point{X: 0, Y: 0}, c: Point{X: 5, Y: 10}, p1: Point{X: 10, Y: -2}}
+	c := q.ToCubic()
+	for i := 0; i <= 10; i++ {
+		s := float64(i) / 10
+		want := Point{
+			X: (1-s)*(1-s)*q.p0.X + 2*s*(1-s)*q.c.X + s*s*q.p1.X,
+			Y: (1-s)*(1-s)*q.p0.Y + 2*s*(1-s)*q.c.Y + s*s*q.p1.Y,
+		}
+		if got := c.at(s); math.Hypot(got.X-want.X, got.Y-want.Y) > 1e-9 {
+			t.Errorf("cubic at %g is %v, quadratic is %v", s, got, want)
+		}
+	}
+}
+
+func TestNormalizeWinding(t *testing.T) {
+	square := func(x, y, size float64, cw bool) []Point {
+		r := []Point{{X: x, Y: y}, {X: x + size, Y: y}, {X: x + size, Y: y + size}, {X: x, Y: y + size}}
+		if cw {
+			Reverse(r)
+		}
+		return r
+	}
+	for _, clockwise := range []bool{false, true} {
+		p := Polygon{
+			Exterior: square(0, 0, 10, true),
+			Holes:    [][]Point{square(1, 1, 2, true), square(5, 5, 2, false)},
+		}
+		// a triangle on the exterior and one on each hole
+		p.Triangles = []Triangle{{0, 1, 2}, {4, 5, 6}, {8, 9, 10}}
+		corners := p.TrianglePoints()
+
+		p.NormalizeWinding(clockwise)
+		if Ring(p.Exterior).IsClockwise() != clockwise {
+			t.Errorf("asking for clockwise %v left the exterior clockwise %v", clockwise, !clockwise)
+		}
+		for i, h := range p.Holes {
+			if Ring(h).IsClockwise() == clockwise {
+				t.Errorf("hole %d winds the same way as the exterior", i)
+			}
+		}
+		// the triangles still name the same corners
+		for i, c := range p.TrianglePoints() {
+			got := map[Point]bool{c[0]: true, c[1]: true, c[2]: true}
+			if !got[corners[i][0]] || !got[corners[i][1]] || !got[corners[i][2]] {
+				t.Errorf("triangle %d has corners %v, want %v", i, c, corners[i])
+			}
+		}
+	}
+}
+
+func TestPathContoursAfterClose(t *testing.T) {
+	polys := convert(t, `<svg><path d="M0 0 L10 0 L10 10 Z M20 20 L30 20 L30 30 Z" fill="#000000"/></svg>`, DefaultOptions())
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want 2", len(polys))
+	}
+	seen := make(map[Point]bool)
+	for _, p := range polys {
+		if len(p.Exterior) != 3 || len(p.Holes) != 0 {
+			t.Errorf("polygon %v isn't a lone triangle", p.Exterior)
+		}
+		if len(p.Triangles) != 1 {
+			t.Errorf("polygon %v has %d triangles, want 1", p.Exterior, len(p.Triangles))
+		}
+		seen[p.Bounds().Min] = true
+	}
+	if !seen[Point{X: 0, Y: 0}] || !seen[Point{X: 20, Y: 20}] {
+		t.Errorf("contours start at %v, want (0, 0) and (20, 20)", seen)
+	}
+}
+
+func TestPathHoleDrawnFirst(t *testing.T) {
+	polys := convert(t, `<svg><path d="M2 2 L2 8 L8 8 L8 2 Z M0 0 L10 0 L10 10 L0 10 Z" fill="#000000"/></svg>`, DefaultOptions())
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	p := polys[0]
+	if b := p.Bounds(); b.Min != (Point{X: 0, Y: 0}) || b.Max != (Point{X: 10, Y: 10}) {
+		t.Errorf("exterior spans %v, want the outer square", b)
+	}
+	if len(p.Holes) != 1 {
+		t.Errorf("got %d holes, want the inner square", len(p.Holes))
+	}
+}
+
+func TestRecenterLargeCoordinates(t *testing.T) {
+	opts := DefaultOptions()
+	opts.RecenterForTriangulation = true
+	polys := convert(t, `<svg><path d="M10000000 10000000 h100 v100 h-100 Z M10000020 10000020 v60 h60 v-60 Z" fill="#000000"/></svg>`, opts)
+	if len(polys) != 1 || len(polys[0].Holes) != 1 {
+		t.Fatalf("got %v, want a square with a hole", polys)
+	}
+	p := polys[0]
+	if b := p.Bounds(); b.Min != (Point{X: 1e7, Y: 1e7}) || b.Max != (Point{X: 1e7 + 100, Y: 1e7 + 100}) {
+		t.Errorf("vertices moved, bounds are %v", b)
+	}
+	area := 0.
+	vs := p.Vertices()
+	for _, tri := range p.Triangles {
+		// Area is twice the signed area
+		area += math.Abs(Ring{vs[tri[0]], vs[tri[1]], vs[tri[2]]}.Area()) / 2
+	}
+	if want := 100.*100 - 60*60; math.Abs(area-want) > 1e-6 {
+		t.Errorf("triangles cover %g, want %g", area, want)
+	}
+}
+
+func TestPathMovesStartRings(t *testing.T) {
+	polys := convert(t, `<svg><path d="M0 0 L10 0 L10 10 M20 20 L30 20 L30 30" fill="#000000"/></svg>`, DefaultOptions())
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want one for each move", len(polys))
+	}
+	for _, p := range polys {
+		// an edge bridging the subpaths would stretch a ring over both
+		if b := p.Bounds(); len(p.Exterior) != 3 || b.Max.X-b.Min.X != 10 {
+			t.Errorf("ring %v isn't one of the triangles", p.Exterior)
+		}
+	}
+}
+
+func TestUnknownLeafSkipped(t *testing.T) {
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	opts.Metrics = &Metrics{}
+	// the mesh's children would fail to convert if they were walked
+	polys := convert(t, `<svg>
+<mesh><meshrow><meshpatch><path d="M0 0 X"/><rect width="oops" height="1"/></meshpatch></meshrow></mesh>
+<rect width="10" height="10"/>
+</svg>`, opts)
+	if len(polys) != 1 {
+		t.Errorf("got %d polygons, want only the rect", len(polys))
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "mesh") {
+		t.Errorf("got warnings %q, want one about the mesh", warnings)
+	}
+	if opts.Metrics.Skipped["mesh"] != 1 {
+		t.Errorf("got skipped %v, want the mesh counted", opts.Metrics.Skipped)
+	}
+}
+
+func TestClipPathWarning(t *testing.T) {
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	polys := convert(t, `<svg><g clip-path="url(#c)"><rect width="10" height="10"/></g></svg>`, opts)
+	if len(polys) != 1 {
+		t.Errorf("got %d polygons, want the unclipped rect", len(polys))
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "clip-path") {
+		t.Errorf("got warnings %q, want one about the clip-path", warnings)
+	}
+}
+
+func TestOpenInputURL(t *testing.T) {
+	const fixture = `<svg><rect width="10" height="10" fill="#ff0000"/></svg>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/map.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, fixture)
+	}))
+	defer srv.Close()
+
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	for _, path := range []string{"/map.svg", "/page"} {
+		r, err := OpenInput(srv.URL+path, opts)
+		if err != nil {
+			t.Fatalf("fetching %s: %v", path, err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(b) != fixture {
+			t.Errorf("%s served '%s', %v", path, b, err)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "text/html") {
+		t.Errorf("got warnings %q, want one about the html content type", warnings)
+	}
+
+	if _, err := OpenInput(srv.URL+"/missing.svg", opts); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got %v fetching a missing file, want a 404 error", err)
+	}
+}
+
+func TestParsePackedDecimals(t *testing.T) {
+	for d, want := range map[string]SVGDParts{
+		"M1.5.5":    {SVGDAbsoluteMovePart{Point{X: 1.5, Y: .5}}},
+		"M.5.5L1.5": nil,
+		"m-1.5-.5":  {SVGDRelativeMovePart{Point{X: -1.5, Y: -.5}}},
+	} {
+		parts, err := NewSVGDReader(strings.NewReader(d)).Parse()
+		if want == nil {
+			if err == nil {
+				t.Errorf("'%s' parsed as %v, want an error for the missing coordinate", d, parts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsing '%s': %v", d, err)
+		} else if !reflect.DeepEqual(parts, want) {
+			t.Errorf("'%s' parsed as %v, want %v", d, parts, want)
+		}
+	}
+}
+
+func TestSmoothQuadraticMatchesQ(t *testing.T) {
+	for smooth, explicit := range map[string]string{
+		"M0 0 Q5 10 10 0 T20 0":       "M0 0 Q5 10 10 0 Q15 -10 20 0",
+		"M0 0 q5 10 10 0 t10 0 t10 0": "M0 0 Q5 10 10 0 Q15 -10 20 0 Q25 10 30 0",
+		"M0 0 L10 0 T20 10":           "M0 0 L10 0 Q10 0 20 10",
+		"M0 0 C1 1 2 2 10 0 T20 0":    "M0 0 C1 1 2 2 10 0 Q10 0 20 0",
+		"M0 0 Q5 10 10 0 M0 5 T10 5":  "M0 0 Q5 10 10 0 M0 5 Q0 5 10 5",
+	} {
+		var rings [2][][]Point
+		for i, d := range []string{smooth, explicit} {
+			parts, err := NewSVGDReader(strings.NewReader(d)).Parse()
+			if err != nil {
+				t.Fatalf("parsing '%s': %v", d, err)
+			}
+			rings[i] = parts.LinearizeRings(0.1)
+		}
+		if !reflect.DeepEqual(rings[0], rings[1]) {
+			t.Errorf("'%s' gives %v, want the same as '%s' %v", smooth, rings[0], explicit, rings[1])
+		}
+	}
+}
+
+func TestCommandArity(t *testing.T) {
+	want := map[rune]int{
+		'M': 2, 'm': 2, 'L': 2, 'l': 2, 'V': 1, 'v': 1, 'H': 1, 'h': 1,
+		'C': 6, 'c': 6, 'Q': 4, 'q': 4, 'T': 2, 't': 2, 'A': 7, 'a': 7,
+		'Z': 0, 'z': 0,
+	}
+	if len(SVGAllCommands) != len(want) {
+		t.Errorf("got %d commands, want %d", len(SVGAllCommands), len(want))
+	}
+	for _, r := range SVGAllCommands {
+		cmd := SVGDCommand(r)
+		n := cmd.Arity()
+		if n != want[r] {
+			t.Errorf("'%c' takes %d operands, want %d", r, n, want[r])
+			continue
+		}
+		// arc flags must be 0 or 1, every other operand may be anything
+		coords := []float64{1, 1, 0, 1, 0, 5, 5}[:n]
+		if _, err := MakePart(cmd, coords...); err != nil {
+			t.Errorf("making '%c' from %v: %v", r, coords, err)
+		}
+		if _, err := MakePart(cmd, append(coords, 1)...); err == nil {
+			t.Errorf("'%c' accepted %d operands", r, n+1)
+		}
+
+		d := "M0 0 " + string(r) + strings.Repeat(" 1", n)
+		if parts, err := NewSVGDReader(strings.NewReader(d)).Parse(); err != nil || len(parts) != 2 {
+			t.Errorf("'%s' parsed as %v, %v", d, parts, err)
+		}
+	}
+	if n := SVGDCommand('X').Arity(); n != -1 {
+		t.Errorf("'X' takes %d operands, want -1", n)
+	}
+}
+
+func TestParseMultiLine(t *testing.T) {
+	single := "M0 0 L10 0 C12 2 12 8 10 10 Z m5 5 h1 v1 z"
+	multi := "\n    M0 0\n    L10 0\r\n\tC12 2\n\t  12 8\n\t  10 10\n    Z\n    m5 5\n    h1\n    v1\n    z\n  "
+	want, err := NewSVGDReader(strings.NewReader(single)).Parse()
+	if err != nil {
+		t.Fatalf("parsing '%s': %v", single, err)
+	}
+	got, err := NewSVGDReader(strings.NewReader(multi)).Parse()
+	if err != nil {
+		t.Fatalf("parsing %q: %v", multi, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%q parsed as %v, want %v", multi, got, want)
+	}
+}
+
+// stuckScanner endlessly repeats a move without reporting the size of what
+// it reads, the way a broken reader would stall the parser
+type stuckScanner struct{ i *int }
+
+func (s stuckScanner) ReadRune() (rune, int, error) {
+	ru := rune("M0 0 "[*s.i%5])
+	*s.i++
+	return ru, 0, nil
+}
+
+func (s stuckScanner) UnreadRune() error {
+	*s.i--
+	return nil
+}
+
+func TestParseNoProgress(t *testing.T) {
+	_, err := NewSVGDReader(stuckScanner{new(int)}).Parse()
+	if !errors.Is(err, ErrNoProgress) {
+		t.Fatalf("got %v, want %v", err, ErrNoProgress)
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Offset != 0 {
+		t.Errorf("got %v, want a ParseError at offset 0", err)
+	}
+}
+
+func FuzzParsePath(f *testing.F) {
+	for _, d := range []string{
+		"M0 0 L10 0 L10 10 Z",
+		"M1.5.5",
+		"M.5.5.5.5",
+		"M-1-2L+3+4",
+		"M1e5-2e-3l-.5.5",
+		"M0,0 c1,1 2,2 3,3 s4,4 5,5",
+		"m0 0a5 5 0 1 1 10 0z",
+		"M1 2 3",
+		"M0 0 L",
+		"M-.-.",
+		"M1..2",
+		"M0 0 A1 1 0 11 5 5",
+		"M 1,5 2,5",
+		"M0 0 Q1 1 2 0 T4 0",
+		"\n\tM 0 0\r\n L 1 1 \n",
+	} {
+		f.Add(d)
+	}
+	f.Fuzz(func(t *testing.T, d string) {
+		parts, err := NewSVGDReader(strings.NewReader(d)).Parse()
+		if err != nil {
+			return
+		}
+		for _, ring := range parts.LinearizeRings(0.1) {
+			for _, p := range ring {
+				if math.IsNaN(p.X) || math.IsNaN(p.Y) {
+					t.Fatalf("'%s' linearizes to NaN", d)
+				}
+			}
+		}
+	})
+}
+
+func TestHiddenShapes(t *testing.T) {
+	polys := convert(t, `<svg>
+<rect id="shown" width="10" height="10"/>
+<rect id="none" display="none" width="10" height="10"/>
+<rect id="hidden" visibility="hidden" width="10" height="10"/>
+<rect id="styled" style="display: none" width="10" height="10"/>
+<g display="none"><rect id="inside" width="10" height="10"/></g>
+<g visibility="hidden"><rect id="overridden" visibility="visible" width="10" height="10"/></g>
+</svg>`, DefaultOptions())
+	ids := map[string]bool{}
+	for _, p := range polys {
+		ids[p.ID] = true
+	}
+	if len(polys) != 2 || !ids["shown"] || !ids["overridden"] {
+		t.Errorf("got shapes %v, want only shown and overridden", ids)
+	}
+}
+
+func TestRectDefaults(t *testing.T) {
+	polys := convert(t, `<svg>
+<rect width="10" height="5"/>
+<rect width="10"/>
+<rect height="10"/>
+</svg>`, DefaultOptions())
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want only the rect with a size", len(polys))
+	}
+	b := polys[0].Bounds()
+	if b.Min.X != 0 || b.Min.Y != 0 || b.Max.X != 10 || b.Max.Y != 5 {
+		t.Errorf("got bounds %v, want the rect at the origin", b)
+	}
+}
+
+func TestBezierSplitLength(t *testing.T) {
+	b := Bezier{p0: Point{X: 0, Y: 0}, c0: Point{X: 0, Y: 10}, c1: Point{X: 10, Y: 10}, p1: Point{X: 10, Y: 0}}
+	l, r := b.Split(0.5)
+	mid := b.at(0.5)
+	if l.p0 != b.p0 || r.p1 != b.p1 || l.p1 != mid || r.p0 != mid {
+		t.Errorf("split into %v and %v, want them to join at %v between the ends of %v", l, r, mid, b)
+	}
+	if whole, halves := b.Length(1e-6), l.Length(1e-6)+r.Length(1e-6); math.Abs(whole-halves) > 1e-4 {
+		t.Errorf("halves are %g long, want %g", halves, whole)
+	}
+
+	line := Bezier{p0: Point{X: 0, Y: 0}, c0: Point{X: 0.75, Y: 1}, c1: Point{X: 1.5, Y: 2}, p1: Point{X: 3, Y: 4}}
+	if got := line.Length(1e-6); math.Abs(got-5) > 1e-6 {
+		t.Errorf("straight curve is %g long, want 5", got)
+	}
+}
+
+func TestLinearizeRings(t *testing.T) {
+	for d, want := range map[string]int{
+		"M0 0 L1 0 L1 1":                             1,
+		"M0 0 L1 0 L1 1 Z M2 2 L3 2 L3 3 Z":          2,
+		"M0 0 L1 0 L1 1 m5 5 l1 0 l0 1 M9 9 H10 V10": 3,
+	} {
+		parts, err := NewSVGDReader(strings.NewReader(d)).Parse()
+		if err != nil {
+			t.Fatalf("parsing '%s': %v", d, err)
+		}
+		rings := parts.LinearizeRings(0.1)
+		if len(rings) != want {
+			t.Errorf("'%s' has %d rings, want %d", d, len(rings), want)
+		}
+		flat := 0
+		for _, r := range rings {
+			flat += len(r)
+		}
+		if n := len(parts.Linearize(0.1)); n != flat {
+			t.Errorf("'%s' flattens to %d points, want the %d of its rings", d, n, flat)
+		}
+	}
+}
+
+func TestSnapNearVertices(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SnapTolerance = 1e-6
+	polys := convert(t, `<svg><path d="M0 0 L10 0 L10 0.000000001 L10 10 L0 10 Z" fill="#000000"/></svg>`, opts)
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	p := polys[0]
+	if len(p.Exterior) != 4 {
+		t.Errorf("got exterior %v, want the close vertices merged", p.Exterior)
+	}
+	covered := 0.
+	for _, tri := range p.TrianglePoints() {
+		a := math.Abs(Ring(tri[:]).Area()) / 2
+		if a < 1e-6 {
+			t.Errorf("triangle %v has no area", tri)
+		}
+		covered += a
+	}
+	if math.Abs(covered-100) > 1e-6 {
+		t.Errorf("triangles cover %g, want 100", covered)
+	}
+}
+
+func TestRelativeAbsoluteMix(t *testing.T) {
+	parts, err := NewSVGDReader(strings.NewReader("m10 10 L20 20 l5 5 H40 v-10 h5 V0 l-5 0")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rings := parts.LinearizeRings(0.1)
+	want := []Point{{X: 10, Y: 10}, {X: 20, Y: 20}, {X: 25, Y: 25}, {X: 40, Y: 25}, {X: 40, Y: 15}, {X: 45, Y: 15}, {X: 45, Y: 0}, {X: 40, Y: 0}}
+	if len(rings) != 1 || len(rings[0]) != len(want) {
+		t.Fatalf("got %v, want %v", rings, want)
+	}
+	for i, p := range rings[0] {
+		if p != want[i] {
+			t.Errorf("vertex %d is at %v, want %v", i, p, want[i])
+		}
+	}
+}
+
+func TestSplitFigureEight(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SplitTouching = true
+	polys := convert(t, `<svg><path d="M0 0 L5 5 L10 0 L10 10 L5 5 L0 10 Z" fill="#ff0000"/></svg>`, opts)
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want the two lobes", len(polys))
+	}
+	got := boundsOf(polys)
+	want := []BoundingBox{
+		{Min: Point{X: 0, Y: 0}, Max: Point{X: 5, Y: 10}},
+		{Min: Point{X: 5, Y: 0}, Max: Point{X: 10, Y: 10}},
+	}
+	for i, p := range polys {
+		if !near(got[i], want[i]) {
+			t.Errorf("lobe %d spans %v, want %v", i, got[i], want[i])
+		}
+		if len(p.Exterior) != 3 || len(p.Triangles) != 1 || p.Fill.Hex() != "#ff0000" {
+			t.Errorf("lobe %v has %d triangles and fill %s, want one red triangle", p.Exterior, len(p.Triangles), p.Fill.Hex())
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	polys := convert(t, `<svg>
+<path id="fr" d="M0 0 L10 0 L10 10 Z" fill="#0000ff"><title>France</title></path>
+<rect id="es" x="20" width="5" height="5" aria-label="Spain"><title>ignored</title></rect>
+<rect id="none" x="40" width="5" height="5"/>
+</svg>`, DefaultOptions())
+	labels := map[string]string{}
+	for _, p := range polys {
+		labels[p.ID] = p.Label
+	}
+	want := map[string]string{"fr": "France", "es": "Spain", "none": ""}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("got labels %v, want %v", labels, want)
+	}
+}
+
+func TestScaleOutput(t *testing.T) {
+	const svg = `<svg><path d="M1 2 L5 2 L5 7 Z" fill="#000000"/></svg>`
+	plain := convert(t, svg, DefaultOptions())
+	opts := DefaultOptions()
+	opts.Scale = 2
+	scaled := convert(t, svg, opts)
+	if len(plain) != 1 || len(scaled) != 1 || len(plain[0].Exterior) != len(scaled[0].Exterior) {
+		t.Fatalf("got %v scaled from %v", scaled, plain)
+	}
+	for i, p := range plain[0].Exterior {
+		if q := scaled[0].Exterior[i]; q.X != 2*p.X || q.Y != 2*p.Y {
+			t.Errorf("vertex %v scaled to %v, want it doubled", p, q)
+		}
+	}
+
+	// recentering happens after the scale
+	opts.Recenter = RecenterMin
+	if b := convert(t, svg, opts)[0].Bounds(); !near(b, BoundingBox{Max: Point{X: 8, Y: 10}}) {
+		t.Errorf("got bounds %v, want the doubled shape at the origin", b)
+	}
+}
+
+func TestCommandAfterCoordinates(t *testing.T) {
+	for _, d := range []string{"M0 0L10 0L10 10Z", "M0,0L10,0L10,10z", "M0 0l10 0v10h-10Z", "M0 0H10V10Z"} {
+		parts, err := parseStrict(d)
+		if err != nil {
+			t.Errorf("parsing '%s': %v", d, err)
+			continue
+		}
+		if rings := parts.LinearizeRings(0.1); len(rings) != 1 || len(rings[0]) < 3 {
+			t.Errorf("'%s' gave %v, want one closed ring", d, rings)
+		}
+	}
+	// the letter after a number is still read as a command
+	if _, err := parseStrict("M0 0 L10 0X"); !errors.Is(err, ErrInvalidCommand) {
+		t.Errorf("'M0 0 L10 0X' fails with %v, want %v", err, ErrInvalidCommand)
+	}
+}
+
+func TestMaxSegmentLength(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxSegmentLength = 5
+	polys := convert(t, `<svg><path d="M0 0 C300 0 300 300 0 300 A150 150 0 0 1 0 0 Z" fill="#000000"/></svg>`, opts)
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	r := Ring(polys[0].Exterior)
+	for i := range r {
+		a, b := r.At(i), r.At(i+1)
+		if d := math.Hypot(b.X-a.X, b.Y-a.Y); d > 5+1e-9 {
+			t.Errorf("segment from %v to %v is %g long, want at most 5", a, b, d)
+		}
+	}
+}
+
+func TestTrianglePointsSquare(t *testing.T) {
+	square := Polygon{
+		Exterior:  []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+		Triangles: []Triangle{{0, 1, 2}, {0, 2, 3}},
+	}
+	want := [][3]Point{
+		{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}},
+		{{X: 0, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+	}
+	if got := square.TrianglePoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// indices past the exterior run on into the holes
+	square.Holes = [][]Point{{{X: 4, Y: 4}, {X: 4, Y: 6}, {X: 6, Y: 6}}}
+	square.Triangles = []Triangle{{0, 4, 6}}
+	if got := square.TrianglePoints(); len(got) != 1 || got[0] != [3]Point{{X: 0, Y: 0}, {X: 4, Y: 4}, {X: 6, Y: 6}} {
+		t.Errorf("got %v, want a triangle reaching into the hole", got)
+	}
+}
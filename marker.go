@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// MarkerEndPolygons places the marker referenced by el's marker-end attribute at
+// the last vertex of points. The <marker> definition is looked up among the ids
+// of root, unless opts already indexes the document.
+//
+// TODO: marker-start and marker-mid
+func MarkerEndPolygons(root, el *svgparser.Element, points []Point, opts Options) ([]Polygon, error) {
+	id, ok := parseURLRef(el.Attributes["marker-end"])
+	if !ok || len(points) < 2 {
+		return nil, nil
+	}
+
+	if opts.ids == nil {
+		opts.ids = indexIDs(root)
+	}
+	marker := opts.ids[id]
+	if marker == nil || marker.Name != "marker" {
+		return nil, fmt.Errorf("marker '%s' not found", id)
+	}
+
+	end := points[len(points)-1]
+	m := Translate(end.X, end.Y)
+
+	switch orient := marker.Attributes["orient"]; orient {
+	case "":
+	case "auto", "auto-start-reverse":
+		prev := end
+		for i := len(points) - 2; i >= 0 && prev.Equals(end); i-- {
+			prev = points[i]
+		}
+		m = m.Multiply(Rotate(math.Atan2(end.Y-prev.Y, end.X-prev.X)))
+	default:
+		deg, err := strconv.ParseFloat(orient, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid marker orient '%s': %v", orient, err)
+		}
+		m = m.Multiply(Rotate(deg * math.Pi / 180))
+	}
+
+	if marker.Attributes["markerUnits"] != "userSpaceOnUse" {
+		sw, err := opts.lengthAttr(el, "stroke-width", 1)
+		if err != nil {
+			return nil, err
+		}
+		m = m.Multiply(Scale(sw, sw))
+	}
+
+	if vbAttr := strings.TrimSpace(marker.Attributes["viewBox"]); vbAttr != "" {
+		vb, err := parseViewBox(vbAttr)
+		if err != nil {
+			return nil, fmt.Errorf("marker: %v", err)
+		}
+		vw, vh := vb[2], vb[3]
+		w, err := floatAttr(marker, "markerWidth", 3)
+		if err != nil {
+			return nil, err
+		}
+		h, err := floatAttr(marker, "markerHeight", 3)
+		if err != nil {
+			return nil, err
+		}
+		if vw > 0 && vh > 0 {
+			// preserveAspectRatio defaults to meet
+			s := math.Min(w/vw, h/vh)
+			m = m.Multiply(Scale(s, s))
+		}
+	}
+
+	refX, err := floatAttr(marker, "refX", 0)
+	if err != nil {
+		return nil, err
+	}
+	refY, err := floatAttr(marker, "refY", 0)
+	if err != nil {
+		return nil, err
+	}
+	m = m.Multiply(Translate(-refX, -refY))
+
+	// the marker contents are drawn in the path's user space
+	m = opts.currentTransform().Multiply(m)
+	opts.transform = &m
+	// the marker is placed by m, not moved with the document's origin
+	opts.Recenter = RecenterNone
+
+	var ret []Polygon
+	for _, child := range marker.Children {
+		polys, err := ExtractPolygons(child, opts)
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, polys...)
+	}
+	return ret, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMarkerEndArrowhead(t *testing.T) {
+	for _, c := range []struct {
+		d   string
+		tip Point
+	}{
+		{"M0 0 L10 0", Point{X: 12, Y: 0}},
+		{"M0 0 L0 10", Point{X: 0, Y: 12}},
+	} {
+		polys := convert(t, `<svg>
+<defs><marker id="arrow" orient="auto" markerUnits="userSpaceOnUse" refY="1"><path d="M0 0 L2 1 L0 2 Z"/></marker></defs>
+<path d="`+c.d+`" fill="none" marker-end="url(#arrow)"/>
+</svg>`, DefaultOptions())
+		if len(polys) != 1 {
+			t.Fatalf("'%s' gives %d polygons, want the arrowhead", c.d, len(polys))
+		}
+		found := false
+		for _, p := range polys[0].Exterior {
+			found = found || math.Hypot(p.X-c.tip.X, p.Y-c.tip.Y) < 1e-9
+		}
+		if !found {
+			t.Errorf("arrowhead %v of '%s' doesn't point at %v", polys[0].Exterior, c.d, c.tip)
+		}
+	}
+}
+
+func TestMarkerEndUsesIndex(t *testing.T) {
+	opts := DefaultOptions()
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<defs><marker id="arrow" markerUnits="userSpaceOnUse"><path d="M0 0 L2 1 L0 2 Z"/></marker></defs>
+<path d="M0 0 L10 0" fill="none" marker-end="url(#arrow)"/>
+</svg>`), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	path := doc.FindAll("path")[1]
+	// the path alone doesn't hold the marker, the index of the document does
+	opts.ids = indexIDs(doc)
+	polys, err := MarkerEndPolygons(path, path, []Point{{0, 0}, {10, 0}}, opts)
+	if err != nil || len(polys) != 1 {
+		t.Errorf("got %d polygons and %v, want the marker found in the index", len(polys), err)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+type Options struct {
+	// bezier parameter increment used when linearizing curves
+	Resolution float64
+	// when positive curves are split further until no segment is longer
+	// than this, in user units
+	MaxSegmentLength float64
+	// arcs are turned into cubic beziers of at most a quarter turn which
+	// are linearized in their place, rather than sampled directly
+	ArcsAsCubics bool
+
+	// accept common malformed input, like a path that doesn't start with a
+	// moveto, instead of failing
+	Lenient bool
+	// skip elements that fail to convert and carry on with the rest, their
+	// errors are returned together as ElementErrors once walking is done
+	SkipInvalid bool
+
+	// when false polygons are returned with their rings only and no triangles
+	Triangulate bool
+	// minimum triangle angle in degrees, when above zero triangulations are
+	// refined with RefineTriangulation which adds vertices to reach it
+	MeshQuality float64
+	// move each polygon to the origin while it is triangulated, for large
+	// coordinates like projected meters where the triangulator loses precision
+	RecenterForTriangulation bool
+	// vertices closer than this are merged before triangulating, so near
+	// coincident points don't make slivers or trip up the triangulator
+	SnapTolerance float64
+	// split shapes whose outline passes through one of its vertices twice,
+	// like a figure eight, into a polygon for each loop
+	SplitTouching bool
+
+	// limits for untrusted input, a document going over one fails with a
+	// LimitError. Zero is unlimited. MaxBytes counts the decompressed size,
+	// MaxDepth counts each use as a level below the element using it and
+	// MaxVertices counts the vertices of every polygon output. A use that
+	// ends up referencing itself fails whatever the limits.
+	MaxElements int
+	MaxBytes    int64
+	MaxDepth    int
+	MaxVertices int
+
+	// multiplies every coordinate of the document, after millimeters and
+	// before recentering. Zero leaves them as they are.
+	Scale float64
+
+	// where ExtractPolygons puts the origin of its output, WalkPolygons writes
+	// polygons before their bounds are known and ignores it. Empty is none.
+	Recenter Recenter
+
+	// when non-nil, filled in with per-phase timings and counts
+	Metrics *Metrics
+
+	// called for anything that converts but won't match the source exactly,
+	// warnings go to stderr when nil
+	Warn func(msg string)
+
+	// called after every shape element with the polygon of its fill, which is
+	// nil when the shape has none or was dropped, and for every unsupported
+	// element skipped with a nil polygon
+	OnElement func(name string, poly *Polygon)
+
+	// emit stroked outlines as additional polygons
+	Strokes bool
+	// override the stroke-linejoin and stroke-miterlimit of every element
+	// when set
+	StrokeLineJoin   LineJoin
+	StrokeMiterLimit float64
+
+	// when set, path geometry is remembered between conversions
+	PathCache *PathCache
+
+	// json output writes fills as their components rather than hex strings
+	FloatColors bool
+
+	// depth given to every vertex by the 3d output formats
+	Z float64
+
+	// indexed output formats merge coincident vertices of each polygon, see
+	// Polygon.Weld
+	Weld bool
+	// 3d output formats write every triangle a second time facing the other
+	// way, so the mesh is seen from behind whatever its winding
+	DoubleSided bool
+
+	// obj and gltf output include planar texture coordinates, spanning each
+	// polygon's bounding box or UVBounds when it is set, usually to the
+	// SceneBounds of the whole document
+	GenerateUVs bool
+	UVBounds    *BoundingBox
+
+	// output coordinates in millimeters from the physical size the root svg
+	// declares with its width, height and viewBox
+	Millimeters bool
+
+	// decimal places vertices are rounded to, both before they are deduped
+	// and when written as text, negative keeps full precision
+	Precision int
+
+	// fill-rule of elements that don't specify one, nonzero when empty
+	FillRule FillRule
+
+	// what to do with shapes that have no area or no triangles, the zero
+	// value drops them
+	EmptyPolygons EmptyPolicy
+
+	// every element with an id in the document being converted
+	ids map[string]*svgparser.Element
+	// what has been counted against the limits so far
+	limits *limits
+	// user space of the element being converted, nil is the identity
+	transform *Matrix
+	// width and height of the viewport percentages are relative to, zero
+	// outside of any svg element
+	viewport [2]float64
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Resolution:    0.1,
+		Triangulate:   true,
+		Lenient:       true,
+		EmptyPolygons: DropEmpty,
+		Precision:     -1,
+	}
+}
+
+// quantize rounds every vertex of p to Precision and removes the duplicates
+// that creates, so what is triangulated is exactly what gets written
+func (o Options) quantize(p *Polygon) {
+	if o.Precision < 0 {
+		return
+	}
+	round := func(r []Point) {
+		for i, v := range r {
+			r[i] = v.Round(o.Precision)
+		}
+	}
+	round(p.Exterior)
+	for _, h := range p.Holes {
+		round(h)
+	}
+	round(p.Steiner)
+	p.RemoveDuplicates()
+}
+
+// decimals is how many decimal places the text formats write
+func (o Options) decimals() int {
+	if o.Precision < 0 {
+		return 6
+	}
+	return o.Precision
+}
+
+type EmptyPolicy string
+
+const (
+	DropEmpty  EmptyPolicy = "drop"
+	KeepEmpty  EmptyPolicy = "keep"
+	ErrorEmpty EmptyPolicy = "error"
+)
+
+func ParseEmptyPolicy(s string) (EmptyPolicy, error) {
+	switch p := EmptyPolicy(s); p {
+	case DropEmpty, KeepEmpty, ErrorEmpty:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown empty polygon policy '%s'", s)
+}
+
+// isEmpty reports whether p covers nothing, without triangulation only the
+// area can be checked
+func (o Options) isEmpty(p Polygon) bool {
+	if Ring(p.Exterior).Area() == 0 {
+		return true
+	}
+	return o.Triangulate && len(p.Triangles) == 0
+}
+
+// skip reports an element that isn't converted, along with everything in it
+func (o Options) skip(el *svgparser.Element) {
+	o.warnf("%s is not supported and is skipped", describeElement(el))
+	if o.Metrics != nil {
+		if o.Metrics.Skipped == nil {
+			o.Metrics.Skipped = make(map[string]int)
+		}
+		o.Metrics.Skipped[el.Name]++
+	}
+}
+
+func (o Options) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if o.Warn != nil {
+		o.Warn(msg)
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+	}
+}
+
+type Metrics struct {
+	Parse       time.Duration `json:"parse"`
+	Linearize   time.Duration `json:"linearize"`
+	Triangulate time.Duration `json:"triangulate"`
+	Encode      time.Duration `json:"encode"`
+
+	Elements  int `json:"elements"`
+	Polygons  int `json:"polygons"`
+	Vertices  int `json:"vertices"`
+	Triangles int `json:"triangles"`
+
+	// elements that aren't supported by name and how many were passed over
+	Skipped map[string]int `json:"skipped,omitempty"`
+}
+
+// add accumulates the timings and counts of o into m
+func (m *Metrics) add(o *Metrics) {
+	m.Parse += o.Parse
+	m.Linearize += o.Linearize
+	m.Triangulate += o.Triangulate
+	m.Encode += o.Encode
+	m.Elements += o.Elements
+	m.Polygons += o.Polygons
+	m.Vertices += o.Vertices
+	m.Triangles += o.Triangles
+	for name, n := range o.Skipped {
+		if m.Skipped == nil {
+			m.Skipped = make(map[string]int)
+		}
+		m.Skipped[name] += n
+	}
+}
+
+func (m *Metrics) String() string {
+	ret := fmt.Sprintf("parse: %v, linearize: %v, triangulate: %v, encode: %v, elements: %d, polygons: %d, vertices: %d, triangles: %d",
+		m.Parse, m.Linearize, m.Triangulate, m.Encode, m.Elements, m.Polygons, m.Vertices, m.Triangles)
+	if len(m.Skipped) > 0 {
+		ret += fmt.Sprintf(", skipped: %v", m.Skipped)
+	}
+	return ret
+}
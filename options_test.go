@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestMetrics(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Metrics = &Metrics{}
+	convert(t, `<svg><path d="M0 0 C10 0 10 10 0 10 Z" fill="#000000"/><rect width="5" height="5"/></svg>`, opts)
+
+	m := opts.Metrics
+	if m.Parse <= 0 || m.Linearize <= 0 || m.Triangulate <= 0 {
+		t.Errorf("got parse %v, linearize %v and triangulate %v, want them timed", m.Parse, m.Linearize, m.Triangulate)
+	}
+	if m.Elements != 2 || m.Polygons != 2 || m.Vertices == 0 || m.Triangles == 0 {
+		t.Errorf("got %d elements, %d polygons, %d vertices and %d triangles", m.Elements, m.Polygons, m.Vertices, m.Triangles)
+	}
+}
+
+func TestOnElement(t *testing.T) {
+	calls := map[string]int{}
+	converted := 0
+	opts := DefaultOptions()
+	opts.Warn = func(string) {}
+	opts.OnElement = func(name string, poly *Polygon) {
+		calls[name]++
+		if poly != nil {
+			converted++
+		}
+	}
+	convert(t, `<svg><g>
+<rect width="5" height="5"/>
+<rect width="5"/>
+<path d="M0 0 L10 0 L10 10 Z" fill="#000000"/>
+<polygon points="20,0 30,0 30,10"/>
+<mesh/>
+</g></svg>`, opts)
+	if calls["rect"] != 2 || calls["path"] != 1 || calls["polygon"] != 1 || calls["mesh"] != 1 || len(calls) != 4 {
+		t.Errorf("called for %v, want each of the five leaf elements", calls)
+	}
+	if converted != 3 {
+		t.Errorf("got %d polygons, want 3", converted)
+	}
+}
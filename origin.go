@@ -0,0 +1,40 @@
+package main
+
+import "fmt"
+
+// Recenter is where ExtractPolygons moves the origin of its output to
+type Recenter string
+
+const (
+	RecenterNone   Recenter = "none"
+	RecenterCenter Recenter = "center"
+	RecenterMin    Recenter = "min"
+)
+
+func ParseRecenter(s string) (Recenter, error) {
+	switch r := Recenter(s); r {
+	case RecenterNone, RecenterCenter, RecenterMin:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown recenter mode '%s'", s)
+}
+
+// RecenterPolygons translates every polygon in place so the center or the
+// minimum corner of the box around all of them is at the origin, returning
+// the translation applied
+func RecenterPolygons(polys []Polygon, mode Recenter) (offset Point) {
+	box := SceneBounds(polys)
+	switch mode {
+	case RecenterCenter:
+		offset = Point{X: -(box.Min.X + box.Max.X) / 2, Y: -(box.Min.Y + box.Max.Y) / 2}
+	case RecenterMin:
+		offset = Point{X: -box.Min.X, Y: -box.Min.Y}
+	default:
+		return
+	}
+	m := Translate(offset.X, offset.Y)
+	for i := range polys {
+		polys[i].Transform(m)
+	}
+	return
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestRecenter(t *testing.T) {
+	const svg = `<svg><rect x="10" y="20" width="10" height="10"/><rect x="40" y="20" width="10" height="30"/></svg>`
+	for _, c := range []struct {
+		mode Recenter
+		want BoundingBox
+	}{
+		{RecenterNone, BoundingBox{Min: Point{X: 10, Y: 20}, Max: Point{X: 50, Y: 50}}},
+		{RecenterCenter, BoundingBox{Min: Point{X: -20, Y: -15}, Max: Point{X: 20, Y: 15}}},
+		{RecenterMin, BoundingBox{Min: Point{X: 0, Y: 0}, Max: Point{X: 40, Y: 30}}},
+	} {
+		opts := DefaultOptions()
+		opts.Recenter = c.mode
+		if got := SceneBounds(convert(t, svg, opts)); !near(got, c.want) {
+			t.Errorf("%s: got bounds %v, want %v", c.mode, got, c.want)
+		}
+	}
+	if _, err := ParseRecenter("middle"); err == nil {
+		t.Error("parsed an unknown mode")
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// indexIDs maps every id in the document to its element, the first element
+// wins when an id is repeated
+func indexIDs(root *svgparser.Element) map[string]*svgparser.Element {
+	ids := make(map[string]*svgparser.Element)
+	stack := []*svgparser.Element{root}
+	for len(stack) > 0 {
+		el := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id := el.Attributes["id"]; id != "" {
+			if _, ok := ids[id]; !ok {
+				ids[id] = el
+			}
+		}
+		// push in reverse so earlier elements are seen first
+		for i := len(el.Children) - 1; i >= 0; i-- {
+			stack = append(stack, el.Children[i])
+		}
+	}
+	return ids
+}
+
+// styleValue returns a property from the element's style attribute
+func styleValue(el *svgparser.Element, name string) string {
+	for _, decl := range strings.Split(el.Attributes["style"], ";") {
+		if k, v, ok := strings.Cut(decl, ":"); ok && strings.TrimSpace(k) == name {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// presentation reads a presentation attribute, a style declaration of the
+// same name takes precedence
+func presentation(el *svgparser.Element, name string) string {
+	if s := styleValue(el, name); s != "" {
+		return s
+	}
+	return strings.TrimSpace(el.Attributes[name])
+}
+
+// opacityOf is the product of the element's opacity and the named fill or
+// stroke opacity, each clamped to [0, 1]
+func opacityOf(el *svgparser.Element, name string) (float64, error) {
+	ret := 1.
+	for _, n := range []string{"opacity", name} {
+		v := el.Attributes[n]
+		if s := styleValue(el, n); s != "" {
+			v = s
+		}
+		o, err := parseOffset(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s '%s': %v", n, v, err)
+		}
+		if strings.TrimSpace(v) == "" {
+			o = 1
+		}
+		ret *= o
+	}
+	return ret, nil
+}
+
+// PaintColor resolves a fill or stroke value to a single color. Paint servers
+// can't be represented on a solid polygon so gradients are averaged over
+// their stops and patterns take the first color painted in their tile.
+func PaintColor(paint string, opts Options) (Color, error) {
+	id, ok := parseURLRef(paint)
+	if !ok {
+		return ParseColor(strings.TrimSpace(paint))
+	}
+
+	el := opts.ids[id]
+	if el == nil {
+		opts.warnf("paint server '%s' not found", id)
+		return Color{}, nil
+	}
+	return paintServerColor(el, opts, 0)
+}
+
+// how many hrefs to follow between paint servers before giving up
+const maxPaintServerDepth = 16
+
+func paintServerColor(el *svgparser.Element, opts Options, depth int) (Color, error) {
+	if depth > maxPaintServerDepth {
+		return Color{}, fmt.Errorf("paint server %s references itself", describeElement(el))
+	}
+
+	// gradients and patterns can borrow their content from another one
+	href := func() *svgparser.Element {
+		if ref := strings.TrimPrefix(el.Attributes["href"], "#"); ref != "" {
+			return opts.ids[ref]
+		}
+		return nil
+	}
+
+	switch el.Name {
+	case "linearGradient", "radialGradient":
+		stops := el.FindAll("stop")
+		if len(stops) == 0 {
+			if ref := href(); ref != nil {
+				return paintServerColor(ref, opts, depth+1)
+			}
+			return Color{}, nil
+		}
+		opts.warnf("%s is averaged to a solid color", describeElement(el))
+		return averageStops(stops)
+	case "pattern":
+		if len(el.Children) == 0 {
+			if ref := href(); ref != nil {
+				return paintServerColor(ref, opts, depth+1)
+			}
+			return Color{}, nil
+		}
+		opts.warnf("%s is replaced by the first color in its tile", describeElement(el))
+		queue := append([]*svgparser.Element(nil), el.Children...)
+		for len(queue) > 0 {
+			child := queue[0]
+			queue = append(queue[1:], child.Children...)
+			if fill := child.Attributes["fill"]; fill != "" && fill != "none" {
+				if id, ok := parseURLRef(fill); ok {
+					if ref := opts.ids[id]; ref != nil {
+						return paintServerColor(ref, opts, depth+1)
+					}
+					continue
+				}
+				return ParseColor(fill)
+			}
+		}
+		return Color{}, nil
+	}
+	return Color{}, fmt.Errorf("%s is not a paint server", describeElement(el))
+}
+
+// averageStops integrates the piecewise linear gradient over [0, 1]
+func averageStops(stops []*svgparser.Element) (avg Color, err error) {
+	offsets, colors, err := parseStops(stops)
+	if err != nil {
+		return
+	}
+
+	add := func(c Color, w float64) {
+		avg.R += c.R * w
+		avg.G += c.G * w
+		avg.B += c.B * w
+		avg.A += c.A * w
+	}
+	// the first and last colors pad out to the ends
+	add(colors[0], offsets[0])
+	add(colors[len(colors)-1], 1-offsets[len(offsets)-1])
+	for i := 1; i < len(stops); i++ {
+		w := (offsets[i] - offsets[i-1]) / 2
+		add(colors[i-1], w)
+		add(colors[i], w)
+	}
+	return
+}
+
+// parseStops reads the offset and color of each gradient stop, offsets are
+// kept from going backwards
+func parseStops(stops []*svgparser.Element) (offsets []float64, colors []Color, err error) {
+	offsets = make([]float64, len(stops))
+	colors = make([]Color, len(stops))
+	for i, stop := range stops {
+		if offsets[i], err = parseOffset(stop.Attributes["offset"]); err != nil {
+			return
+		}
+		// offsets can't go backwards
+		if i > 0 && offsets[i] < offsets[i-1] {
+			offsets[i] = offsets[i-1]
+		}
+
+		col := stop.Attributes["stop-color"]
+		if s := styleValue(stop, "stop-color"); s != "" {
+			col = s
+		}
+		if col == "" {
+			col = "#000"
+		}
+		if colors[i], err = ParseColor(col); err != nil {
+			return
+		}
+
+		opacity := stop.Attributes["stop-opacity"]
+		if s := styleValue(stop, "stop-opacity"); s != "" {
+			opacity = s
+		}
+		if opacity != "" {
+			if colors[i].A, err = strconv.ParseFloat(opacity, 64); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// parseOffset reads a stop offset as a number or percentage clamped to [0, 1]
+func parseOffset(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	scale := 1.
+	if strings.HasSuffix(s, "%") {
+		s, scale = strings.TrimSuffix(s, "%"), 0.01
+	}
+	o, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	o *= scale
+	if o < 0 {
+		return 0, nil
+	} else if o > 1 {
+		return 1, nil
+	}
+	return o, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestPatternFill(t *testing.T) {
+	polys := convert(t, `<svg>
+<defs>
+<pattern id="base" width="4" height="4" patternUnits="userSpaceOnUse" patternTransform="rotate(45)"><rect width="2" height="2" fill="#00ff00"/></pattern>
+<pattern id="alias" href="#base"/>
+</defs>
+<rect width="10" height="10" fill="url(#base)"/>
+<rect x="20" width="10" height="10" fill="url(#alias)"/>
+</svg>`, DefaultOptions())
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want the two rects and no tile", len(polys))
+	}
+	for _, p := range polys {
+		if p.Fill.Hex() != "#00ff00" {
+			t.Errorf("rect at %v is filled %s, want the tile's #00ff00", p.Bounds().Min, p.Fill.Hex())
+		}
+		if len(p.Triangles) != 2 {
+			t.Errorf("rect at %v has %d triangles, want 2", p.Bounds().Min, len(p.Triangles))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+// RenderPreview rasterizes the triangles of polys in order over a white
+// background, scaled so the scene fits in size pixels along its longer side
+func RenderPreview(polys []Polygon, size int) *image.RGBA {
+	var points []Point
+	for _, p := range polys {
+		points = append(points, p.Vertices()...)
+	}
+	bounds := Ring(points).Bounds()
+	extent := math.Max(bounds.Max.X-bounds.Min.X, bounds.Max.Y-bounds.Min.Y)
+	scale := 1.
+	if extent > 0 {
+		scale = float64(size) / extent
+	}
+	w := int(math.Max(1, math.Ceil((bounds.Max.X-bounds.Min.X)*scale)))
+	h := int(math.Max(1, math.Ceil((bounds.Max.Y-bounds.Min.Y)*scale)))
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for _, p := range polys {
+		fill := image.NewUniform(p.Fill)
+		vertices := p.Vertices()
+		for _, t := range p.Triangles {
+			var pixels [3]Point
+			for k, i := range t {
+				pixels[k] = Point{X: (vertices[i].X - bounds.Min.X) * scale, Y: (vertices[i].Y - bounds.Min.Y) * scale}
+			}
+			fillTriangle(img, pixels, fill)
+		}
+	}
+	return img
+}
+
+// fillTriangle draws the pixels whose centers are inside t one row at a time
+func fillTriangle(img draw.Image, t [3]Point, src image.Image) {
+	minY := math.Min(t[0].Y, math.Min(t[1].Y, t[2].Y))
+	maxY := math.Max(t[0].Y, math.Max(t[1].Y, t[2].Y))
+	for y := int(math.Ceil(minY - 0.5)); float64(y)+0.5 < maxY; y++ {
+		yc := float64(y) + 0.5
+		left, right := math.Inf(1), math.Inf(-1)
+		for k := range t {
+			a, b := t[k], t[(k+1)%3]
+			// edges are half open in y so a vertex on the row counts once
+			if (a.Y <= yc) == (b.Y <= yc) {
+				continue
+			}
+			x := a.X + (yc-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			left, right = math.Min(left, x), math.Max(right, x)
+		}
+		if left > right {
+			continue
+		}
+		x0, x1 := int(math.Ceil(left-0.5)), int(math.Ceil(right-0.5))
+		draw.Draw(img, image.Rect(x0, y, x1, y+1), src, image.Point{}, draw.Over)
+	}
+}
+
+// WritePreview renders polys with RenderPreview and encodes them as a png
+func WritePreview(w io.Writer, polys []Polygon, size int) error {
+	return png.Encode(w, RenderPreview(polys, size))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestRenderPreview(t *testing.T) {
+	polys := convert(t, `<svg>
+<rect width="10" height="10" fill="#ff0000"/>
+<rect x="20" width="10" height="10" fill="#000000"/>
+</svg>`, DefaultOptions())
+	img := RenderPreview(polys, 30)
+	if b := img.Bounds(); b.Dx() != 30 || b.Dy() != 10 {
+		t.Fatalf("got a %v image, want 30 by 10", b)
+	}
+	for _, c := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{5, 5, color.RGBA{R: 255, A: 255}},
+		{25, 5, color.RGBA{A: 255}},
+		{15, 5, color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+	} {
+		if got := img.RGBAAt(c.x, c.y); got != c.want {
+			t.Errorf("pixel (%d, %d) is %v, want %v", c.x, c.y, got, c.want)
+		}
+	}
+}
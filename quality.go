@@ -0,0 +1,390 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// RefineTriangulation turns the triangulation of poly into a constrained
+// Delaunay one and then inserts Steiner points at the circumcenters of
+// triangles with an angle below minAngle degrees. The new points are appended
+// to poly.Steiner so the vertex count grows, often by several times the input.
+// Bounds above about 20 degrees are not guaranteed to be reached near sharp
+// input corners, refinement stops after a fixed budget of new points.
+func RefineTriangulation(poly *Polygon, minAngle float64) {
+	if len(poly.Triangles) == 0 || minAngle <= 0 {
+		return
+	}
+
+	c := cdt{
+		pts:   poly.Vertices(),
+		fixed: make(map[[2]int]bool),
+		adj:   make(map[[2]int][]int),
+		skip:  make(map[[3]int]bool),
+	}
+	base := len(c.pts)
+
+	// every ring edge is a constraint, shared points use their first index
+	// like Triangulate does
+	first := make(map[Point]int)
+	for i, p := range c.pts {
+		if _, ok := first[p]; !ok {
+			first[p] = i
+		}
+	}
+	for _, ring := range append([][]Point{poly.Exterior}, poly.Holes...) {
+		for i := range ring {
+			if a, b := first[ring[i]], first[ring[(i+1)%len(ring)]]; a != b {
+				c.fixed[edgeKey(a, b)] = true
+			}
+		}
+	}
+
+	// work counter-clockwise and put the original orientation back at the end
+	flipped := false
+	for _, t := range poly.Triangles {
+		if o := cross(c.pts[t[0]], c.pts[t[1]], c.pts[t[2]]); o < 0 {
+			t[1], t[2] = t[2], t[1]
+			flipped = true
+		} else if o == 0 {
+			// degenerate triangles can't be refined around
+			continue
+		}
+		c.addTriangle(t)
+	}
+
+	min, max := c.pts[0], c.pts[0]
+	for _, p := range c.pts {
+		min.X, min.Y = math.Min(min.X, p.X), math.Min(min.Y, p.Y)
+		max.X, max.Y = math.Max(max.X, p.X), math.Max(max.Y, p.Y)
+	}
+	c.minEdge = math.Hypot(max.X-min.X, max.Y-min.Y) * 1e-3
+
+	var edges [][2]int
+	for _, t := range c.tris {
+		edges = append(edges, [2]int{t[0], t[1]}, [2]int{t[1], t[2]}, [2]int{t[2], t[0]})
+	}
+	c.legalize(edges)
+	c.refine(minAngle*math.Pi/180, 10*base+100)
+
+	// the vertices already held Steiner points of any earlier refinement
+	poly.Steiner = append([]Point(nil), c.pts[base-len(poly.Steiner):]...)
+	poly.Triangles = make([]Triangle, 0, len(c.tris))
+	for _, t := range c.tris {
+		if flipped {
+			t[1], t[2] = t[2], t[1]
+		}
+		poly.Triangles = append(poly.Triangles, t)
+	}
+}
+
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// cdt is a counter-clockwise triangle mesh with constrained edges
+type cdt struct {
+	pts  []Point
+	tris []Triangle
+	// constrained edges
+	fixed map[[2]int]bool
+	// triangles on each side of an edge
+	adj map[[2]int][]int
+	// triangles whose circumcenter couldn't be inserted
+	skip map[[3]int]bool
+	// edges shorter than this are left alone so refinement terminates
+	minEdge float64
+}
+
+func (c *cdt) addTriangle(t Triangle) int {
+	c.tris = append(c.tris, t)
+	i := len(c.tris) - 1
+	c.link(i)
+	return i
+}
+
+func (c *cdt) link(i int) {
+	t := c.tris[i]
+	for k := 0; k < 3; k++ {
+		e := edgeKey(t[k], t[(k+1)%3])
+		c.adj[e] = append(c.adj[e], i)
+	}
+}
+
+func (c *cdt) unlink(i int) {
+	t := c.tris[i]
+	for k := 0; k < 3; k++ {
+		e := edgeKey(t[k], t[(k+1)%3])
+		ts := c.adj[e]
+		for j := range ts {
+			if ts[j] == i {
+				ts = append(ts[:j], ts[j+1:]...)
+				break
+			}
+		}
+		if len(ts) == 0 {
+			delete(c.adj, e)
+		} else {
+			c.adj[e] = ts
+		}
+	}
+}
+
+// replace swaps triangle i for t keeping the adjacency up to date
+func (c *cdt) replace(i int, t Triangle) {
+	c.unlink(i)
+	c.tris[i] = t
+	c.link(i)
+}
+
+// rotate returns t starting at a, ok is false if a isn't a vertex of t
+func rotate(t Triangle, a int) (Triangle, bool) {
+	for k := 0; k < 3; k++ {
+		if t[k] == a {
+			return Triangle{t[k], t[(k+1)%3], t[(k+2)%3]}, true
+		}
+	}
+	return t, false
+}
+
+// inCircle reports whether d is strictly inside the circumcircle of the
+// counter-clockwise triangle a, b, c
+func inCircle(a, b, c, d Point) bool {
+	ax, ay := a.X-d.X, a.Y-d.Y
+	bx, by := b.X-d.X, b.Y-d.Y
+	cx, cy := c.X-d.X, c.Y-d.Y
+	det := (ax*ax+ay*ay)*(bx*cy-cx*by) -
+		(bx*bx+by*by)*(ax*cy-cx*ay) +
+		(cx*cx+cy*cy)*(ax*by-bx*ay)
+	return det > 1e-12
+}
+
+// legalize flips unconstrained edges until every one of them is Delaunay
+func (c *cdt) legalize(edges [][2]int) {
+	for len(edges) > 0 {
+		e := edges[len(edges)-1]
+		edges = edges[:len(edges)-1]
+
+		ts := c.adj[edgeKey(e[0], e[1])]
+		if c.fixed[edgeKey(e[0], e[1])] || len(ts) != 2 {
+			continue
+		}
+		// t0 is u, v, p and t1 is v, u, q
+		t0, _ := rotate(c.tris[ts[0]], e[0])
+		if t0[1] != e[1] {
+			t0, _ = rotate(c.tris[ts[0]], e[1])
+		}
+		u, v, p := t0[0], t0[1], t0[2]
+		t1, _ := rotate(c.tris[ts[1]], v)
+		q := t1[2]
+
+		if !inCircle(c.pts[u], c.pts[v], c.pts[p], c.pts[q]) {
+			continue
+		}
+		c.replace(ts[0], Triangle{u, q, p})
+		c.replace(ts[1], Triangle{q, v, p})
+		edges = append(edges, [2]int{u, q}, [2]int{q, v}, [2]int{v, p}, [2]int{p, u})
+	}
+}
+
+// locate finds the triangle containing p, edge is the index of the vertex
+// opposite the edge p lies on or -1 when p is inside
+func (c *cdt) locate(p Point) (tri, edge int) {
+	for i, t := range c.tris {
+		a, b, d := c.pts[t[0]], c.pts[t[1]], c.pts[t[2]]
+		area := cross(a, b, d)
+		l := [3]float64{cross(b, d, p) / area, cross(d, a, p) / area, cross(a, b, p) / area}
+		if l[0] < -1e-9 || l[1] < -1e-9 || l[2] < -1e-9 {
+			continue
+		}
+		for k := range l {
+			if l[k] < 1e-9 {
+				return i, k
+			}
+		}
+		return i, -1
+	}
+	return -1, -1
+}
+
+// insert adds p inside triangle tri, or on the edge opposite vertex edge
+func (c *cdt) insert(p Point, tri, edge int) {
+	n := len(c.pts)
+	c.pts = append(c.pts, p)
+
+	if edge < 0 {
+		t := c.tris[tri]
+		c.replace(tri, Triangle{t[0], t[1], n})
+		c.addTriangle(Triangle{t[1], t[2], n})
+		c.addTriangle(Triangle{t[2], t[0], n})
+		c.legalize([][2]int{{t[0], t[1]}, {t[1], t[2]}, {t[2], t[0]}})
+		return
+	}
+
+	t := c.tris[tri]
+	a, b := t[(edge+1)%3], t[(edge+2)%3]
+	key := edgeKey(a, b)
+	if c.fixed[key] {
+		delete(c.fixed, key)
+		c.fixed[edgeKey(a, n)] = true
+		c.fixed[edgeKey(n, b)] = true
+	}
+	var edges [][2]int
+	for _, i := range append([]int(nil), c.adj[key]...) {
+		// u, v, w with u, v being the split edge
+		s, _ := rotate(c.tris[i], a)
+		if s[1] != b {
+			s, _ = rotate(c.tris[i], b)
+		}
+		u, v, w := s[0], s[1], s[2]
+		c.replace(i, Triangle{u, n, w})
+		c.addTriangle(Triangle{n, v, w})
+		edges = append(edges, [2]int{v, w}, [2]int{w, u})
+	}
+	c.legalize(edges)
+}
+
+// segments lists the constrained edges in a fixed order so refinement is
+// repeatable
+func (c *cdt) segments() [][2]int {
+	ret := make([][2]int, 0, len(c.fixed))
+	for e := range c.fixed {
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i][0] != ret[j][0] {
+			return ret[i][0] < ret[j][0]
+		}
+		return ret[i][1] < ret[j][1]
+	})
+	return ret
+}
+
+// encroached reports whether p is inside the diametral circle of segment e
+func (c *cdt) encroached(e [2]int, p Point) bool {
+	a, b := c.pts[e[0]], c.pts[e[1]]
+	return (a.X-p.X)*(b.X-p.X)+(a.Y-p.Y)*(b.Y-p.Y) < 0
+}
+
+func (c *cdt) length(e [2]int) float64 {
+	a, b := c.pts[e[0]], c.pts[e[1]]
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// splitSegment inserts the midpoint of a constrained edge
+func (c *cdt) splitSegment(e [2]int) {
+	a, b := c.pts[e[0]], c.pts[e[1]]
+	tri := c.adj[e][0]
+	t := c.tris[tri]
+	edge := 0
+	for k := 0; k < 3; k++ {
+		if t[k] != e[0] && t[k] != e[1] {
+			edge = k
+		}
+	}
+	c.insert(Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}, tri, edge)
+}
+
+// encroachedSegment returns a constrained edge with a vertex of its own
+// triangle inside its diametral circle
+func (c *cdt) encroachedSegment() ([2]int, bool) {
+	for _, e := range c.segments() {
+		if c.length(e) < 2*c.minEdge {
+			continue
+		}
+		for _, i := range c.adj[e] {
+			for _, v := range c.tris[i] {
+				if v != e[0] && v != e[1] && c.encroached(e, c.pts[v]) {
+					return e, true
+				}
+			}
+		}
+	}
+	return [2]int{}, false
+}
+
+// worstTriangle returns the triangle with the smallest angle under bound, or
+// -1 when there is none left to refine
+func (c *cdt) worstTriangle(bound float64) int {
+	worst, worstAngle := -1, bound
+	for i, t := range c.tris {
+		if c.skip[sortedTriangle(t)] {
+			continue
+		}
+		a, b, d := c.pts[t[0]], c.pts[t[1]], c.pts[t[2]]
+		la, lb, ld := math.Hypot(b.X-d.X, b.Y-d.Y), math.Hypot(a.X-d.X, a.Y-d.Y), math.Hypot(a.X-b.X, a.Y-b.Y)
+		if math.Min(la, math.Min(lb, ld)) < c.minEdge {
+			continue
+		}
+		// the smallest angle is opposite the shortest edge
+		s := math.Min(la, math.Min(lb, ld))
+		l1, l2 := la+lb+ld-s-math.Max(la, math.Max(lb, ld)), math.Max(la, math.Max(lb, ld))
+		angle := math.Acos(math.Max(-1, math.Min(1, (l1*l1+l2*l2-s*s)/(2*l1*l2))))
+		if angle < worstAngle {
+			worst, worstAngle = i, angle
+		}
+	}
+	return worst
+}
+
+func sortedTriangle(t Triangle) [3]int {
+	s := [3]int(t)
+	if s[0] > s[1] {
+		s[0], s[1] = s[1], s[0]
+	}
+	if s[1] > s[2] {
+		s[1], s[2] = s[2], s[1]
+	}
+	if s[0] > s[1] {
+		s[0], s[1] = s[1], s[0]
+	}
+	return s
+}
+
+func circumcenter(a, b, c Point) Point {
+	bx, by := b.X-a.X, b.Y-a.Y
+	cx, cy := c.X-a.X, c.Y-a.Y
+	d := 2 * (bx*cy - by*cx)
+	b2, c2 := bx*bx+by*by, cx*cx+cy*cy
+	return Point{X: a.X + (cy*b2-by*c2)/d, Y: a.Y + (bx*c2-cx*b2)/d}
+}
+
+// refine is Ruppert's algorithm, encroached segments are split before any
+// bad triangle is
+func (c *cdt) refine(bound float64, budget int) {
+	for inserted := 0; inserted < budget; inserted++ {
+		if e, ok := c.encroachedSegment(); ok {
+			c.splitSegment(e)
+			continue
+		}
+
+		i := c.worstTriangle(bound)
+		if i < 0 {
+			return
+		}
+		t := c.tris[i]
+		cc := circumcenter(c.pts[t[0]], c.pts[t[1]], c.pts[t[2]])
+
+		split := false
+		for _, e := range c.segments() {
+			if len(c.adj[e]) > 0 && c.length(e) >= 2*c.minEdge && c.encroached(e, cc) {
+				c.splitSegment(e)
+				split = true
+				break
+			}
+		}
+		if split {
+			continue
+		}
+
+		if tri, edge := c.locate(cc); tri >= 0 {
+			c.insert(cc, tri, edge)
+		} else {
+			c.skip[sortedTriangle(t)] = true
+			inserted--
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// minAngle is the smallest angle in degrees of any triangle of p
+func minAngle(p Polygon) float64 {
+	min := 180.
+	for _, tri := range p.TrianglePoints() {
+		for k := range tri {
+			a, b, c := tri[k], tri[(k+1)%3], tri[(k+2)%3]
+			u, v := Point{X: b.X - a.X, Y: b.Y - a.Y}, Point{X: c.X - a.X, Y: c.Y - a.Y}
+			angle := math.Abs(math.Atan2(u.X*v.Y-u.Y*v.X, u.X*v.X+u.Y*v.Y)) * 180 / math.Pi
+			min = math.Min(min, angle)
+		}
+	}
+	return min
+}
+
+func TestRefineTriangulationMinAngle(t *testing.T) {
+	const svg = `<svg><path d="M0 0 L100 0 L100 8 L0 8 Z" fill="#000000"/></svg>`
+	coarse := convert(t, svg, DefaultOptions())
+	opts := DefaultOptions()
+	opts.MeshQuality = 20
+	fine := convert(t, svg, opts)
+	if len(coarse) != 1 || len(fine) != 1 {
+		t.Fatalf("got %d and %d polygons, want one each", len(coarse), len(fine))
+	}
+
+	before, after := minAngle(coarse[0]), minAngle(fine[0])
+	if after <= before || after < 15 {
+		t.Errorf("refining raised the minimum angle from %.2f to %.2f, want it near 20", before, after)
+	}
+	if len(fine[0].Steiner) == 0 {
+		t.Error("refining added no Steiner points")
+	}
+
+	// the refined triangles still cover the same area
+	area := 0.
+	for _, tri := range fine[0].TrianglePoints() {
+		area += math.Abs(Ring(tri[:]).Area()) / 2
+	}
+	if math.Abs(area-800) > 1e-6 {
+		t.Errorf("refined triangles cover %g, want 800", area)
+	}
+}
+
+func TestRefineTriangulationTwice(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MeshQuality = 10
+	polys := convert(t, `<svg><path d="M0 0 L100 0 L100 8 L0 8 Z" fill="#000000"/></svg>`, opts)
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	p := polys[0]
+	triangles := append([]Triangle(nil), p.Triangles...)
+	steiner := append([]Point(nil), p.Steiner...)
+
+	// refining a copy further leaves the original alone
+	q := p
+	RefineTriangulation(&q, 20)
+	if !reflect.DeepEqual(p.Triangles, triangles) || !reflect.DeepEqual(p.Steiner, steiner) {
+		t.Error("refining a copy changed the original")
+	}
+	if len(q.Steiner) < len(p.Steiner) {
+		t.Errorf("got %d Steiner points, want at least the %d of the first refinement", len(q.Steiner), len(p.Steiner))
+	}
+	area := 0.
+	for _, tri := range q.TrianglePoints() {
+		area += math.Abs(Ring(tri[:]).Area()) / 2
+	}
+	if math.Abs(area-800) > 1e-6 {
+		t.Errorf("refined triangles cover %g, want 800", area)
+	}
+}
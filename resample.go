@@ -0,0 +1,66 @@
+package main
+
+import "math"
+
+// ResampleByArcLength redistributes the points of an open polyline so they
+// are evenly spaced along it. The spacing is adjusted so a whole number of
+// steps fits, both ends are kept and corners between them may be cut.
+func ResampleByArcLength(points []Point, spacing float64) []Point {
+	if len(points) < 2 || spacing <= 0 {
+		return points
+	}
+	total := polylineLength(points, false)
+	n := int(math.Max(1, math.Round(total/spacing)))
+	step := total / float64(n)
+
+	ret := make([]Point, 0, n+1)
+	ret = append(ret, points[0])
+	// distance along the line to the start of segment k and the next sample
+	walked, next := 0., step
+	for k := 1; k < len(points) && len(ret) < n; k++ {
+		a, b := points[k-1], points[k]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		for len(ret) < n && next <= walked+length {
+			ret = append(ret, a.Lerp(b, (next-walked)/length))
+			next += step
+		}
+		walked += length
+	}
+	return append(ret, points[len(points)-1])
+}
+
+// Resample returns n points spaced equally by arc length around the ring,
+// starting at its first point, so rings resampled to the same n can be
+// interpolated vertex by vertex with Point.Lerp
+func (r Ring) Resample(n int) Ring {
+	if len(r) == 0 || n <= 0 {
+		return nil
+	}
+	total := polylineLength(r, true)
+	if total == 0 {
+		ret := make(Ring, n)
+		for i := range ret {
+			ret[i] = r[0]
+		}
+		return ret
+	}
+	step := total / float64(n)
+
+	ret := make(Ring, 0, n)
+	walked, next := 0., 0.
+	// the last segment wraps back around to the first point
+	for k := 0; k < len(r) && len(ret) < n; k++ {
+		a, b := r.At(k), r.At(k+1)
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		for len(ret) < n && next < walked+length {
+			ret = append(ret, a.Lerp(b, (next-walked)/length))
+			next += step
+		}
+		walked += length
+	}
+	// rounding can leave the last sample just past the end
+	for len(ret) < n {
+		ret = append(ret, r[0])
+	}
+	return ret
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResampleByArcLength(t *testing.T) {
+	// parameter steps bunch up where the curve bends
+	curve := Bezier{p0: Point{X: 0, Y: 0}, c0: Point{X: 100, Y: 0}, c1: Point{X: 0, Y: 10}, p1: Point{X: 100, Y: 10}}
+	points := curve.Linearize(0.01, 0)
+	const spacing = 2.
+	got := ResampleByArcLength(points, spacing)
+	if len(got) < 3 {
+		t.Fatalf("got %v, want the curve resampled", got)
+	}
+	if !got[0].Equals(points[0]) || !got[len(got)-1].Equals(points[len(points)-1]) {
+		t.Errorf("resampled from %v to %v, want the ends kept", got[0], got[len(got)-1])
+	}
+	total := polylineLength(points, false)
+	step := total / float64(len(got)-1)
+	if math.Abs(step-spacing) > spacing/2 {
+		t.Errorf("steps of %g, want about %g", step, spacing)
+	}
+	for i := 1; i < len(got); i++ {
+		// corners are cut so a chord may fall a little short of the step
+		if d := math.Hypot(got[i].X-got[i-1].X, got[i].Y-got[i-1].Y); d > step+1e-9 || d < 0.9*step {
+			t.Errorf("points %d and %d are %g apart, want %g", i-1, i, d, step)
+		}
+	}
+}
+
+func TestRingResample(t *testing.T) {
+	square := Ring{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}}
+	got := square.Resample(8)
+	want := Ring{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 2}, {X: 4, Y: 4}, {X: 2, Y: 4}, {X: 0, Y: 4}, {X: 0, Y: 2}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i].X-want[i].X) > 1e-9 || math.Abs(got[i].Y-want[i].Y) > 1e-9 {
+			t.Errorf("point %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+	if p := (Point{X: 0, Y: 0}).Lerp(Point{X: 4, Y: 2}, 0.25); p.X != 1 || p.Y != 0.5 {
+		t.Errorf("lerp gave %v, want (1, 0.5)", p)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+// TriangleStrips greedily groups the triangles of poly into strips indexing
+// Vertices(). Each strip decodes the usual way, triangle k being
+// strip[k], strip[k+1], strip[k+2] with the first two swapped when k is odd, so
+// every triangle keeps its winding.
+func TriangleStrips(poly Polygon) (strips [][]int) {
+	// triangles on each side of an edge
+	adj := make(map[[2]int][]int)
+	for i, t := range poly.Triangles {
+		for k := 0; k < 3; k++ {
+			e := edgeKey(t[k], t[(k+1)%3])
+			adj[e] = append(adj[e], i)
+		}
+	}
+	used := make([]bool, len(poly.Triangles))
+
+	// next finds an unused triangle continuing the strip s
+	next := func(s []int) (int, int) {
+		// index of the triangle being added
+		k := len(s) - 2
+		p, q := s[len(s)-2], s[len(s)-1]
+		for _, i := range adj[edgeKey(p, q)] {
+			if used[i] {
+				continue
+			}
+			t := poly.Triangles[i]
+			r := t[0] + t[1] + t[2] - p - q
+			decoded := Triangle{p, q, r}
+			if k%2 == 1 {
+				decoded = Triangle{q, p, r}
+			}
+			if sameTriangle(decoded, t) {
+				return i, r
+			}
+		}
+		return -1, 0
+	}
+
+	for i, t := range poly.Triangles {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+
+		// start from the rotation that can be continued, if any
+		s := []int{t[0], t[1], t[2]}
+		for r := 0; r < 3; r++ {
+			rot, _ := rotate(t, t[r])
+			if j, _ := next(rot[:]); j >= 0 {
+				s = []int{rot[0], rot[1], rot[2]}
+				break
+			}
+		}
+		for {
+			j, r := next(s)
+			if j < 0 {
+				break
+			}
+			used[j] = true
+			s = append(s, r)
+		}
+		strips = append(strips, s)
+	}
+	return
+}
+
+// sameTriangle reports whether a and b are the same triangle with the same
+// winding
+func sameTriangle(a, b Triangle) bool {
+	for k := 0; k < 3; k++ {
+		if a[0] == b[k] && a[1] == b[(k+1)%3] && a[2] == b[(k+2)%3] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestTriangleStripsReconstruct(t *testing.T) {
+	polys := convert(t, `<svg>
+<path d="M0 0 L10 0 L20 5 L30 0 L40 10 L20 20 L0 10 Z M15 8 L25 8 L20 12 Z" fill="#000000"/>
+</svg>`, DefaultOptions())
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(polys))
+	}
+	p := polys[0]
+
+	var decoded []Triangle
+	for _, s := range TriangleStrips(p) {
+		for k := 0; k+2 < len(s); k++ {
+			tri := Triangle{s[k], s[k+1], s[k+2]}
+			if k%2 == 1 {
+				tri[0], tri[1] = tri[1], tri[0]
+			}
+			decoded = append(decoded, tri)
+		}
+	}
+	if len(decoded) != len(p.Triangles) {
+		t.Fatalf("strips decode to %d triangles, want %d", len(decoded), len(p.Triangles))
+	}
+	used := make([]bool, len(decoded))
+	for _, want := range p.Triangles {
+		found := false
+		for i, got := range decoded {
+			if !used[i] && sameTriangle(got, want) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("triangle %v is missing from the strips", want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+type LineJoin string
+
+const (
+	MiterJoin LineJoin = "miter"
+	RoundJoin LineJoin = "round"
+	BevelJoin LineJoin = "bevel"
+)
+
+// default stroke-miterlimit from the svg spec
+const DefaultMiterLimit = 4.
+
+// angle covered by each triangle of a round join
+const roundJoinStep = math.Pi / 16
+
+func ParseLineJoin(s string) (LineJoin, error) {
+	switch j := LineJoin(s); j {
+	case MiterJoin, RoundJoin, BevelJoin:
+		return j, nil
+	}
+	return "", fmt.Errorf("unknown stroke-linejoin '%s'", s)
+}
+
+type Stroke struct {
+	Width      float64
+	Join       LineJoin
+	MiterLimit float64
+	// on and off lengths, nil for a solid stroke
+	Dashes     []float64
+	DashOffset float64
+}
+
+// StrokeFromElement resolves the stroke style of el, values set in opts
+// override the element's attributes
+func StrokeFromElement(el *svgparser.Element, opts Options) (s Stroke, err error) {
+	if s.Width, err = opts.lengthAttr(el, "stroke-width", 1); err != nil {
+		return
+	}
+
+	s.Join = opts.StrokeLineJoin
+	if s.Join == "" {
+		if attr := el.Attributes["stroke-linejoin"]; attr != "" {
+			if s.Join, err = ParseLineJoin(attr); err != nil {
+				return
+			}
+		} else {
+			s.Join = MiterJoin
+		}
+	}
+
+	s.MiterLimit = opts.StrokeMiterLimit
+	if s.MiterLimit <= 0 {
+		if s.MiterLimit, err = floatAttr(el, "stroke-miterlimit", DefaultMiterLimit); err != nil {
+			return
+		}
+	}
+
+	if s.Dashes, err = parseDashArray(el.Attributes["stroke-dasharray"]); err != nil {
+		return
+	}
+	s.DashOffset, err = floatAttr(el, "stroke-dashoffset", 0)
+	return
+}
+
+// strokeMesh accumulates triangles, keeping each one counter-clockwise
+type strokeMesh struct {
+	Polygon
+}
+
+func (m *strokeMesh) vertex(p Point) int {
+	m.Exterior = append(m.Exterior, p)
+	return len(m.Exterior) - 1
+}
+
+// add appends another mesh
+func (m *strokeMesh) add(p Polygon) {
+	offset := len(m.Exterior)
+	m.Exterior = append(m.Exterior, p.Exterior...)
+	for _, t := range p.Triangles {
+		m.Triangles = append(m.Triangles, Triangle{t[0] + offset, t[1] + offset, t[2] + offset})
+	}
+}
+
+func (m *strokeMesh) triangle(a, b, c int) {
+	A, B, C := m.Exterior[a], m.Exterior[b], m.Exterior[c]
+	if (B.X-A.X)*(C.Y-A.Y)-(B.Y-A.Y)*(C.X-A.X) < 0 {
+		b, c = c, b
+	}
+	m.Triangles = append(m.Triangles, Triangle{a, b, c})
+}
+
+func segmentNormal(a, b Point) Point {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return Point{}
+	}
+	return Point{X: -dy / l, Y: dx / l}
+}
+
+// join fills the wedge on the outside of the corner at p between the
+// incoming segment with normal na and the outgoing one with normal nb
+func (m *strokeMesh) join(p, na, nb Point, s Stroke) {
+	h := s.Width / 2
+	turn := na.X*nb.Y - na.Y*nb.X
+	if turn == 0 && na.X*nb.X+na.Y*nb.Y > 0 {
+		// straight through
+		return
+	}
+	// the outside of the corner is opposite the direction of the turn
+	side := 1.
+	if turn > 0 {
+		side = -1
+	}
+	oa := Point{X: side * na.X, Y: side * na.Y}
+	ob := Point{X: side * nb.X, Y: side * nb.Y}
+
+	c := m.vertex(p)
+	a := m.vertex(Point{X: p.X + h*oa.X, Y: p.Y + h*oa.Y})
+	b := m.vertex(Point{X: p.X + h*ob.X, Y: p.Y + h*ob.Y})
+
+	switch s.Join {
+	case RoundJoin:
+		start := math.Atan2(oa.Y, oa.X)
+		sweep := math.Atan2(ob.Y, ob.X) - start
+		for sweep > math.Pi {
+			sweep -= 2 * math.Pi
+		}
+		for sweep < -math.Pi {
+			sweep += 2 * math.Pi
+		}
+		n := int(math.Ceil(math.Abs(sweep) / roundJoinStep))
+		prev := a
+		for i := 1; i < n; i++ {
+			t := start + sweep*float64(i)/float64(n)
+			next := m.vertex(Point{X: p.X + h*math.Cos(t), Y: p.Y + h*math.Sin(t)})
+			m.triangle(c, prev, next)
+			prev = next
+		}
+		m.triangle(c, prev, b)
+		return
+	case MiterJoin:
+		mx, my := oa.X+ob.X, oa.Y+ob.Y
+		ml := math.Hypot(mx, my)
+		if ml == 0 {
+			break
+		}
+		mx, my = mx/ml, my/ml
+		// ratio of the miter length to the stroke width is 1/sin(theta/2),
+		// which is also 1/cos of the angle between the miter and a normal
+		cos := mx*oa.X + my*oa.Y
+		if cos <= 0 || 1/cos > s.MiterLimit {
+			break
+		}
+		tip := m.vertex(Point{X: p.X + h/cos*mx, Y: p.Y + h/cos*my})
+		m.triangle(c, a, tip)
+		m.triangle(c, tip, b)
+		return
+	}
+	m.triangle(c, a, b)
+}
+
+// StrokePolygon expands the polyline through points into a ribbon mesh of the
+// stroke's width. When closed the last point joins back to the first.
+//
+// TODO: line caps other than butt
+func StrokePolygon(points []Point, closed bool, s Stroke) Polygon {
+	var m strokeMesh
+	points = RemoveDuplicates(points, func(p, q Point) bool { return p.Equals(q) })
+	if closed && len(points) > 1 && points[0].Equals(points[len(points)-1]) {
+		points = points[:len(points)-1]
+	}
+	if len(points) < 2 || s.Width <= 0 {
+		return m.Polygon
+	}
+
+	segments := len(points) - 1
+	if closed {
+		segments = len(points)
+	}
+
+	h := s.Width / 2
+	normals := make([]Point, segments)
+	for i := 0; i < segments; i++ {
+		a, b := Ring(points).At(i), Ring(points).At(i+1)
+		n := segmentNormal(a, b)
+		normals[i] = n
+
+		a0 := m.vertex(Point{X: a.X + h*n.X, Y: a.Y + h*n.Y})
+		a1 := m.vertex(Point{X: a.X - h*n.X, Y: a.Y - h*n.Y})
+		b0 := m.vertex(Point{X: b.X + h*n.X, Y: b.Y + h*n.Y})
+		b1 := m.vertex(Point{X: b.X - h*n.X, Y: b.Y - h*n.Y})
+		m.triangle(a0, a1, b1)
+		m.triangle(b1, b0, a0)
+	}
+
+	for i := 1; i < segments; i++ {
+		m.join(points[i], normals[i-1], normals[i], s)
+	}
+	if closed {
+		m.join(points[0], normals[segments-1], normals[0], s)
+	}
+	return m.Polygon
+}
+
+// strokeContour is a polyline to stroke, the ends of a closed one are joined
+type strokeContour struct {
+	points []Point
+	closed bool
+}
+
+// pathContours splits a path into its subpaths, those ending back where they
+// started are closed
+func pathContours(el *svgparser.Element, opts Options) (contours []strokeContour, err error) {
+	rings, err := linearizePathElement(el, opts)
+	for _, r := range rings {
+		contours = append(contours, strokeContour{r, len(r) > 2 && r[0].Equals(r[len(r)-1])})
+	}
+	return
+}
+
+// StrokeFromShapeElement returns the stroke outline of a shape element, or nil
+// when it isn't a shape or isn't stroked
+func StrokeFromShapeElement(el *svgparser.Element, opts Options) (*Polygon, error) {
+	paint := el.Attributes["stroke"]
+	if paint == "" || paint == "none" {
+		return nil, nil
+	}
+
+	m := opts.currentTransform()
+	var contours []strokeContour
+	var err error
+	switch el.Name {
+	case "path":
+		contours, err = pathContours(el, opts)
+	case "polygon", "polyline":
+		var points []Point
+		points, err = parsePoints(el.Attributes["points"])
+		contours = []strokeContour{{points, el.Name == "polygon"}}
+	case "rect":
+		var rect *Polygon
+		// rects come back already transformed
+		if rect, err = PolygonFromRectElement(el, opts); rect != nil {
+			contours = []strokeContour{{rect.Exterior, true}}
+		}
+		m = Identity
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// lengths along the path are in terms of pathLength when it is set
+	lengthScale := 1.
+	if el.Name == "path" {
+		pathLength, err := floatAttr(el, "pathLength", 0)
+		if err != nil {
+			return nil, err
+		}
+		if pathLength > 0 {
+			total := 0.
+			for _, c := range contours {
+				total += polylineLength(c.points, c.closed)
+			}
+			lengthScale = total / pathLength
+		}
+	}
+	for _, c := range contours {
+		for i, p := range c.points {
+			c.points[i] = m.Apply(p)
+		}
+	}
+
+	s, err := StrokeFromElement(el, opts)
+	if err != nil {
+		return nil, err
+	}
+	// approximate the width under non-uniform scales by the mean scale
+	scale := math.Sqrt(math.Abs(opts.currentTransform().Determinant()))
+	s.Width *= scale
+	for i := range s.Dashes {
+		s.Dashes[i] *= scale * lengthScale
+	}
+
+	// subpaths are stroked separately, each with the dash pattern restarted
+	var mesh strokeMesh
+	for _, c := range contours {
+		if s.Dashes == nil {
+			mesh.add(StrokePolygon(c.points, c.closed, s))
+			continue
+		}
+		for _, dash := range DashPolyline(c.points, c.closed, s.Dashes, s.DashOffset*scale*lengthScale) {
+			mesh.add(StrokePolygon(dash, false, s))
+		}
+	}
+	poly := mesh.Polygon
+	if len(poly.Triangles) == 0 {
+		return nil, nil
+	}
+	if poly.Fill, err = PaintColor(paint, opts); err != nil {
+		return nil, err
+	}
+	opacity, err := opacityOf(el, "stroke-opacity")
+	if err != nil {
+		return nil, err
+	}
+	poly.Fill.A *= opacity
+	return &poly, nil
+}
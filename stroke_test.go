@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStrokeSubpathsSeparately(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Strokes = true
+	polys := convert(t, `<svg><path d="M0 0 L10 0 M0 20 L10 20" fill="none" stroke="#000000" stroke-width="2"/></svg>`, opts)
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want the stroke", len(polys))
+	}
+	band := func(p Point) int {
+		switch {
+		case p.Y >= -1 && p.Y <= 1:
+			return 0
+		case p.Y >= 19 && p.Y <= 21:
+			return 1
+		}
+		return -1
+	}
+	if len(polys[0].Triangles) == 0 {
+		t.Fatal("stroke has no triangles")
+	}
+	vs := polys[0].Vertices()
+	for _, tri := range polys[0].Triangles {
+		a, b, c := band(vs[tri[0]]), band(vs[tri[1]]), band(vs[tri[2]])
+		if a < 0 || a != b || b != c {
+			t.Errorf("triangle %v bridges the subpaths", tri)
+		}
+	}
+}
+
+func TestStrokeMiterLimit(t *testing.T) {
+	// the corner at (10, 0) turns back almost on itself
+	points := []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 1}}
+	reach := func(limit float64) (x float64) {
+		poly := StrokePolygon(points, false, Stroke{Width: 1, Join: MiterJoin, MiterLimit: limit})
+		for _, p := range poly.Exterior {
+			x = math.Max(x, p.X)
+		}
+		return
+	}
+	if x := reach(DefaultMiterLimit); x > 10.5+1e-9 {
+		t.Errorf("miter reaches %g past the limit, want a bevel within 10.5", x)
+	}
+	if x := reach(1000); x <= 11 {
+		t.Errorf("miter reaches %g under a generous limit, want a spike", x)
+	}
+}
+
+func TestStrokePathLength(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Strokes = true
+	// every dash of a straight line is one quad of two triangles
+	for pathLength, dashes := range map[string]int{"": 10, "100": 10, "50": 5, "200": 20} {
+		attr := ""
+		if pathLength != "" {
+			attr = ` pathLength="` + pathLength + `"`
+		}
+		polys := convert(t, `<svg><path d="M0 0 L100 0" fill="none" stroke="#000000" stroke-dasharray="5 5"`+attr+`/></svg>`, opts)
+		if len(polys) != 1 || len(polys[0].Triangles) != 2*dashes {
+			t.Errorf("pathLength '%s' gives %v, want %d dashes", pathLength, polys, dashes)
+		}
+	}
+}
+
+func TestStrokeWidthUnits(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Strokes = true
+	for width, want := range map[string]float64{
+		`stroke-width="2px"`:   2,
+		`stroke-width="0.5mm"`: 0.5 * 96 / 25.4,
+		``:                     1,
+	} {
+		polys := convert(t, `<svg><path d="M0 0 L10 0" fill="none" stroke="#000000" `+width+`/></svg>`, opts)
+		if len(polys) != 1 {
+			t.Errorf("%s: got %d polygons, want the stroke", width, len(polys))
+			continue
+		}
+		b := polys[0].Bounds()
+		if math.Abs(b.Max.Y-b.Min.Y-want) > 1e-9 || math.Abs(b.Max.X-b.Min.X-10) > 1e-9 {
+			t.Errorf("%s: ribbon spans %v, want %g wide along the line", width, b, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+var (
+	cssCommentParser  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssSelectorParser = regexp.MustCompile(`^([A-Za-z][\w-]*)?((?:[.#][\w-]+)*)$`)
+	cssPartParser     = regexp.MustCompile(`[.#][\w-]+`)
+)
+
+// cssSelector is a compound selector such as rect.land#uk, any part may be
+// empty
+type cssSelector struct {
+	name    string
+	id      string
+	classes []string
+}
+
+func (s cssSelector) specificity() int {
+	ret := 10 * len(s.classes)
+	if s.id != "" {
+		ret += 100
+	}
+	if s.name != "" {
+		ret++
+	}
+	return ret
+}
+
+func (s cssSelector) matches(el *svgparser.Element) bool {
+	if s.name != "" && s.name != el.Name {
+		return false
+	}
+	if s.id != "" && s.id != el.Attributes["id"] {
+		return false
+	}
+	classes := strings.Fields(el.Attributes["class"])
+	for _, c := range s.classes {
+		found := false
+		for _, k := range classes {
+			found = found || k == c
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type cssRule struct {
+	selector     cssSelector
+	declarations [][2]string
+}
+
+// parseStylesheet reads the rules of a <style> element. Only element, class
+// and id selectors are understood, rules using anything else are returned in
+// skipped.
+func parseStylesheet(css string) (rules []cssRule, skipped []string) {
+	css = cssCommentParser.ReplaceAllString(css, "")
+	for _, block := range strings.Split(css, "}") {
+		selectors, body, ok := strings.Cut(block, "{")
+		if !ok {
+			continue
+		}
+
+		var declarations [][2]string
+		for _, decl := range strings.Split(body, ";") {
+			if k, v, ok := strings.Cut(decl, ":"); ok {
+				v = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important"))
+				declarations = append(declarations, [2]string{strings.TrimSpace(k), v})
+			}
+		}
+
+		for _, sel := range strings.Split(selectors, ",") {
+			sel = strings.TrimSpace(sel)
+			if sel == "" {
+				continue
+			}
+			m := cssSelectorParser.FindStringSubmatch(sel)
+			if m == nil {
+				skipped = append(skipped, sel)
+				continue
+			}
+			s := cssSelector{name: m[1]}
+			for _, part := range cssPartParser.FindAllString(m[2], -1) {
+				if part[0] == '#' {
+					s.id = part[1:]
+				} else {
+					s.classes = append(s.classes, part[1:])
+				}
+			}
+			rules = append(rules, cssRule{selector: s, declarations: declarations})
+		}
+	}
+	return
+}
+
+// applyStylesheets copies the declarations of every <style> element in the
+// document onto the elements they select as attributes. Attributes already on
+// an element are kept, later and more specific rules win between rules.
+func applyStylesheets(root *svgparser.Element, opts Options) {
+	var rules []cssRule
+	for _, style := range root.FindAll("style") {
+		if t := style.Attributes["type"]; t != "" && t != "text/css" {
+			continue
+		}
+		r, skipped := parseStylesheet(style.Content)
+		for _, s := range skipped {
+			opts.warnf("css selector '%s' is not supported and is ignored", s)
+		}
+		rules = append(rules, r...)
+	}
+	if len(rules) == 0 {
+		return
+	}
+	// a stable sort keeps document order between equal specificities
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].selector.specificity() < rules[j].selector.specificity()
+	})
+
+	stack := []*svgparser.Element{root}
+	for len(stack) > 0 {
+		el := stack[len(stack)-1]
+		stack = append(stack[:len(stack)-1], el.Children...)
+
+		styled := make(map[string]string)
+		for _, r := range rules {
+			if r.selector.matches(el) {
+				for _, d := range r.declarations {
+					styled[d[0]] = d[1]
+				}
+			}
+		}
+		if len(styled) > 0 && el.Attributes == nil {
+			el.Attributes = make(map[string]string)
+		}
+		for k, v := range styled {
+			if _, ok := el.Attributes[k]; !ok {
+				el.Attributes[k] = v
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStylesheetClass(t *testing.T) {
+	polys := convert(t, `<svg>
+<style>.land { fill:green } rect.sea { fill: #0000ff }</style>
+<rect class="land" x="0" y="0" width="10" height="10"/>
+<rect class="coast land" x="20" y="0" width="10" height="10"/>
+<rect class="land" x="40" y="0" width="10" height="10" fill="#ff0000"/>
+<rect class="sea" x="60" y="0" width="10" height="10"/>
+</svg>`, DefaultOptions())
+	want := map[float64]string{0: "#008000", 20: "#008000", 40: "#ff0000", 60: "#0000ff"}
+	if len(polys) != len(want) {
+		t.Fatalf("got %d polygons, want %d", len(polys), len(want))
+	}
+	for _, p := range polys {
+		x := p.Exterior[0].X
+		for _, q := range p.Exterior {
+			x = math.Min(x, q.X)
+		}
+		if p.Fill.Hex() != want[x] {
+			t.Errorf("rect at %g is filled %s, want %s", x, p.Fill.Hex(), want[x])
+		}
+	}
+}
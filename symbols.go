@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// ExtractSymbols converts every <symbol> with an id in the document on its
+// own, as if each were drawn by a <use> with no position or size, and returns
+// their polygons keyed by id. Symbols are in the space of their viewBox.
+func ExtractSymbols(root *svgparser.Element, opts Options) (map[string][]Polygon, error) {
+	if opts.ids == nil {
+		opts.ids = indexIDs(root)
+	}
+	if opts.limits == nil {
+		opts.limits = &limits{}
+	}
+	ret := make(map[string][]Polygon)
+	for _, symbol := range root.FindAll("symbol") {
+		id := symbol.Attributes["id"]
+		if id == "" {
+			continue
+		}
+		base := opts.currentTransform()
+		if opts.Scale != 0 {
+			base = Scale(opts.Scale, opts.Scale).Multiply(base)
+		}
+		m, err := useTransform(&svgparser.Element{Name: "use"}, symbol, base)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", describeElement(symbol), err)
+		}
+
+		symbolOpts := opts
+		symbolOpts.transform = &m
+		polys := []Polygon{}
+		for _, child := range symbol.Children {
+			err := WalkPolygons(child, symbolOpts, func(p Polygon) error {
+				polys = append(polys, p)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		ret[id] = polys
+	}
+	return ret, nil
+}
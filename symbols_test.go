@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractSymbols(t *testing.T) {
+	opts := DefaultOptions()
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<symbol id="square" viewBox="0 0 10 10"><rect width="10" height="10" fill="#ff0000"/></symbol>
+<symbol id="pair" viewBox="0 0 20 10">
+<rect width="5" height="5" fill="#00ff00"/>
+<path d="M10 0 L20 0 L20 10 Z" fill="#0000ff"/>
+</symbol>
+<rect x="100" width="10" height="10"/>
+</svg>`), opts)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+	symbols, err := ExtractSymbols(doc, opts)
+	if err != nil {
+		t.Fatalf("extracting symbols: %v", err)
+	}
+	if len(symbols) != 2 || len(symbols["square"]) != 1 || len(symbols["pair"]) != 2 {
+		t.Fatalf("got %v, want square with 1 polygon and pair with 2", symbols)
+	}
+	if b := symbols["square"][0].Bounds(); b.Min != (Point{X: 0, Y: 0}) || b.Max != (Point{X: 10, Y: 10}) {
+		t.Errorf("square spans %v, want its viewBox", b)
+	}
+	for _, p := range symbols["pair"] {
+		if b := p.Bounds(); b.Max.X > 20 || b.Max.Y > 10 {
+			t.Errorf("pair has a polygon spanning %v outside its viewBox", b)
+		}
+	}
+}
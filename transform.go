@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Matrix is a 2d affine transform using the svg matrix(a b c d e f) layout
+type Matrix struct {
+	A, B, C, D, E, F float64
+}
+
+var Identity = Matrix{A: 1, D: 1}
+
+func Translate(x, y float64) Matrix {
+	return Matrix{A: 1, D: 1, E: x, F: y}
+}
+
+func Scale(sx, sy float64) Matrix {
+	return Matrix{A: sx, D: sy}
+}
+
+// Rotate returns a rotation by theta radians
+func Rotate(theta float64) Matrix {
+	sin, cos := math.Sincos(theta)
+	return Matrix{A: cos, B: sin, C: -sin, D: cos}
+}
+
+// Multiply returns m * n, which applies n first and then m
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		A: m.A*n.A + m.C*n.B,
+		B: m.B*n.A + m.D*n.B,
+		C: m.A*n.C + m.C*n.D,
+		D: m.B*n.C + m.D*n.D,
+		E: m.A*n.E + m.C*n.F + m.E,
+		F: m.B*n.E + m.D*n.F + m.F,
+	}
+}
+
+// Apply transforms p, matrix(a b c d e f) lists the columns so b and c are
+// the shears of x and y respectively: x' = ax + cy + e, y' = bx + dy + f
+func (m Matrix) Apply(p Point) Point {
+	return Point{
+		X: m.A*p.X + m.C*p.Y + m.E,
+		Y: m.B*p.X + m.D*p.Y + m.F,
+	}
+}
+
+func (m Matrix) Determinant() float64 {
+	return m.A*m.D - m.B*m.C
+}
+
+// Inverse returns the transform undoing m, ok is false when m collapses the
+// plane onto a line or point and can't be undone
+func (m Matrix) Inverse() (inv Matrix, ok bool) {
+	det := m.Determinant()
+	if det == 0 {
+		return Identity, false
+	}
+	inv = Matrix{A: m.D / det, B: -m.B / det, C: -m.C / det, D: m.A / det}
+	inv.E = -(inv.A*m.E + inv.C*m.F)
+	inv.F = -(inv.B*m.E + inv.D*m.F)
+	return inv, true
+}
+
+// Transform applies m to every vertex of the polygon in place. A mirroring
+// transform would reverse the winding of every ring and triangle, so they are
+// turned back to their original orientation afterwards.
+func (p *Polygon) Transform(m Matrix) {
+	mirror := m.Determinant() < 0
+	clockwise := Ring(p.Exterior).IsClockwise()
+	for i, v := range p.Exterior {
+		p.Exterior[i] = m.Apply(v)
+	}
+	for _, h := range p.Holes {
+		for i, v := range h {
+			h[i] = m.Apply(v)
+		}
+	}
+	for i, v := range p.Steiner {
+		p.Steiner[i] = m.Apply(v)
+	}
+	if mirror {
+		p.NormalizeWinding(clockwise)
+		for i, t := range p.Triangles {
+			p.Triangles[i] = Triangle{t[0], t[2], t[1]}
+		}
+	}
+}
+
+var (
+	transformParser = regexp.MustCompile(`(matrix|translate|scale|rotate|skewX|skewY)\s*\(([^)]*)\)`)
+	numberParser    = regexp.MustCompile(`[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?`)
+	argumentParser  = regexp.MustCompile(numberParser.String() + `(deg|grad|rad|turn)?`)
+)
+
+// degreesPer converts the css angle units to degrees
+var degreesPer = map[string]float64{
+	"":     1,
+	"deg":  1,
+	"grad": 360. / 400,
+	"rad":  180 / math.Pi,
+	"turn": 360,
+}
+
+// ParseTransform reads an svg transform list, the functions are applied right
+// to left so the result is their product in the order written
+func ParseTransform(s string) (Matrix, error) {
+	m := Identity
+	last := 0
+	for _, match := range transformParser.FindAllStringSubmatchIndex(s, -1) {
+		if gap := s[last:match[0]]; strings.Trim(gap, " \t\r\n,") != "" {
+			return Identity, fmt.Errorf("invalid transform '%s'", s)
+		}
+		last = match[1]
+
+		name := s[match[2]:match[3]]
+		var args []float64
+		for i, arg := range argumentParser.FindAllStringSubmatch(s[match[4]:match[5]], -1) {
+			f, err := strconv.ParseFloat(strings.TrimSuffix(arg[0], arg[3]), 64)
+			if err != nil {
+				return Identity, err
+			}
+			// only the angles of rotate and skew take units, given in degrees
+			angle := (name == "rotate" && i == 0) || name == "skewX" || name == "skewY"
+			if arg[3] != "" && !angle {
+				return Identity, fmt.Errorf("%s argument '%s' can't have a unit", name, arg[0])
+			}
+			args = append(args, f*degreesPer[arg[3]])
+		}
+
+		t, err := transformFunction(name, args)
+		if err != nil {
+			return Identity, err
+		}
+		m = m.Multiply(t)
+	}
+	if strings.TrimSpace(s[last:]) != "" {
+		return Identity, fmt.Errorf("invalid transform '%s'", s)
+	}
+	return m, nil
+}
+
+func transformFunction(name string, args []float64) (Matrix, error) {
+	arity := func(counts ...int) error {
+		for _, c := range counts {
+			if len(args) == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s takes %v arguments, got %d", name, counts, len(args))
+	}
+	deg := math.Pi / 180
+
+	switch name {
+	case "matrix":
+		if err := arity(6); err != nil {
+			return Identity, err
+		}
+		return Matrix{A: args[0], B: args[1], C: args[2], D: args[3], E: args[4], F: args[5]}, nil
+	case "translate":
+		if err := arity(1, 2); err != nil {
+			return Identity, err
+		}
+		args = append(args, 0)
+		return Translate(args[0], args[1]), nil
+	case "scale":
+		if err := arity(1, 2); err != nil {
+			return Identity, err
+		}
+		args = append(args, args[0])
+		return Scale(args[0], args[1]), nil
+	case "rotate":
+		if err := arity(1, 3); err != nil {
+			return Identity, err
+		}
+		r := Rotate(args[0] * deg)
+		if len(args) == 3 {
+			r = Translate(args[1], args[2]).Multiply(r).Multiply(Translate(-args[1], -args[2]))
+		}
+		return r, nil
+	case "skewX":
+		if err := arity(1); err != nil {
+			return Identity, err
+		}
+		return Matrix{A: 1, C: math.Tan(args[0] * deg), D: 1}, nil
+	case "skewY":
+		if err := arity(1); err != nil {
+			return Identity, err
+		}
+		return Matrix{A: 1, B: math.Tan(args[0] * deg), D: 1}, nil
+	}
+	return Identity, fmt.Errorf("unknown transform function '%s'", name)
+}
+
+// currentTransform is the transform accumulated from the ancestors of the
+// element being converted
+func (o Options) currentTransform() Matrix {
+	if o.transform == nil {
+		return Identity
+	}
+	return *o.transform
+}
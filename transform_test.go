@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseTransformMatrix(t *testing.T) {
+	// x' = a x + c y + e, y' = b x + d y + f
+	for _, s := range []string{"matrix(1 0.5 2 1 10 20)", "matrix(1,.5,2,1,10,20)"} {
+		m, err := ParseTransform(s)
+		if err != nil {
+			t.Fatalf("parsing '%s': %v", s, err)
+		}
+		for _, c := range []struct{ p, want Point }{
+			{Point{X: 0, Y: 0}, Point{X: 10, Y: 20}},
+			{Point{X: 1, Y: 1}, Point{X: 13, Y: 21.5}},
+			{Point{X: 2, Y: -1}, Point{X: 10, Y: 20}},
+		} {
+			if got := m.Apply(c.p); got != c.want {
+				t.Errorf("'%s' maps %v to %v, want %v", s, c.p, got, c.want)
+			}
+		}
+	}
+}
+
+func TestTransformedDonut(t *testing.T) {
+	polys := convert(t, `<svg><path transform="translate(100 50) rotate(30) scale(2)" d="M0 0 L10 0 L10 10 L0 10 Z M4 4 L4 6 L6 6 L6 4 Z" fill="#000000"/></svg>`, DefaultOptions())
+	if len(polys) != 1 || len(polys[0].Holes) != 1 {
+		t.Fatalf("got %v, want a donut", polys)
+	}
+	centroid := func(r []Point) (c Point) {
+		for _, p := range r {
+			c.X, c.Y = c.X+p.X/float64(len(r)), c.Y+p.Y/float64(len(r))
+		}
+		return
+	}
+	m, _ := ParseTransform("translate(100 50) rotate(30) scale(2)")
+	want := m.Apply(Point{X: 5, Y: 5})
+	for name, r := range map[string][]Point{"exterior": polys[0].Exterior, "hole": polys[0].Holes[0]} {
+		if c := centroid(r); math.Hypot(c.X-want.X, c.Y-want.Y) > 1e-9 {
+			t.Errorf("%s is centered on %v, want %v", name, c, want)
+		}
+	}
+	if len(polys[0].Triangles) == 0 {
+		t.Error("the donut wasn't triangulated")
+	}
+}
+
+func TestRootTransform(t *testing.T) {
+	polys := convert(t, `<svg transform="translate(100 50)">
+<rect width="10" height="10"/>
+<g><path d="M20 0 L30 0 L30 10 Z" fill="#000000"/></g>
+</svg>`, DefaultOptions())
+	if len(polys) != 2 {
+		t.Fatalf("got %d polygons, want 2", len(polys))
+	}
+	mins := map[Point]bool{}
+	for _, p := range polys {
+		mins[p.Bounds().Min] = true
+	}
+	for _, want := range []Point{{X: 100, Y: 50}, {X: 120, Y: 50}} {
+		if !mins[want] {
+			t.Errorf("no shape starts at %v, got %v", want, mins)
+		}
+	}
+}
+
+func TestAngleUnits(t *testing.T) {
+	want, err := ParseTransform("rotate(180deg)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Point{X: 3, Y: 4}
+	w := want.Apply(p)
+	if math.Hypot(w.X+3, w.Y+4) > 1e-9 {
+		t.Fatalf("180deg maps %v to %v, want (-3, -4)", p, w)
+	}
+	for _, s := range []string{"rotate(0.5turn)", "rotate(180)", "rotate(200grad)", "rotate(3.141592653589793rad)"} {
+		m, err := ParseTransform(s)
+		if err != nil {
+			t.Errorf("parsing '%s': %v", s, err)
+		} else if got := m.Apply(p); math.Hypot(got.X-w.X, got.Y-w.Y) > 1e-9 {
+			t.Errorf("'%s' maps %v to %v, want %v", s, p, got, w)
+		}
+	}
+	if _, err := ParseTransform("translate(1turn)"); err == nil {
+		t.Error("translate accepted an angle")
+	}
+}
+
+func TestMirroredDonut(t *testing.T) {
+	polys := convert(t, `<svg><path transform="scale(-1,1)" d="M0 0 L10 0 L10 10 L0 10 Z M4 4 L4 6 L6 6 L6 4 Z" fill="#000000"/></svg>`, DefaultOptions())
+	if len(polys) != 1 || len(polys[0].Holes) != 1 {
+		t.Fatalf("got %v, want a donut", polys)
+	}
+	p := polys[0]
+	if Ring(p.Exterior).Area() < 0 || Ring(p.Holes[0]).Area() > 0 {
+		t.Errorf("exterior winds %g and hole %g after the mirror", Ring(p.Exterior).Area(), Ring(p.Holes[0]).Area())
+	}
+	covered := 0.
+	for _, tri := range p.TrianglePoints() {
+		covered += math.Abs(Ring(tri[:]).Area()) / 2
+		c := Point{X: (tri[0].X + tri[1].X + tri[2].X) / 3, Y: (tri[0].Y + tri[1].Y + tri[2].Y) / 3}
+		if c.X < -4 && c.X > -6 && c.Y > 4 && c.Y < 6 {
+			t.Errorf("triangle %v is inside the hole", tri)
+		}
+	}
+	if math.Abs(covered-96) > 1e-9 {
+		t.Errorf("triangles cover %g, want 96", covered)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Union merges the area covered by polys into a single polygon with holes
+// and triangulates it. It fails when the polygons don't all touch, see
+// UnionPolygons for the separate pieces.
+func Union(polys []Polygon) (Polygon, error) {
+	ret, err := UnionPolygons(polys)
+	if err != nil {
+		return Polygon{}, err
+	} else if len(ret) != 1 {
+		return Polygon{}, fmt.Errorf("union has %d separate outlines", len(ret))
+	}
+	return ret[0], nil
+}
+
+// UnionPolygons merges the area covered by polys, returning a triangulated
+// polygon for every separate outline filled with the first polygon's fill.
+// Holes are subtracted from their own polygon only, so another polygon
+// covering a hole fills it.
+//
+// Every edge is split where it crosses another, the pieces with the union on
+// exactly one side are kept and chained back into rings.
+func UnionPolygons(polys []Polygon) ([]Polygon, error) {
+	var rings [][]Point
+	for _, p := range polys {
+		rings = append(rings, p.Exterior)
+		rings = append(rings, p.Holes...)
+	}
+	var points []Point
+	for _, r := range rings {
+		points = append(points, r...)
+	}
+	box := Ring(points).Bounds()
+	// how far from an edge its sides are sampled
+	eps := 1e-7 * math.Max(1, math.Hypot(box.Max.X-box.Min.X, box.Max.Y-box.Min.Y))
+
+	inside := func(q Point) bool {
+		for _, p := range polys {
+			if windingNumber(p.Exterior, q) == 0 {
+				continue
+			}
+			covered := true
+			for _, h := range p.Holes {
+				if windingNumber(h, q) != 0 {
+					covered = false
+					break
+				}
+			}
+			if covered {
+				return true
+			}
+		}
+		return false
+	}
+
+	// keep each piece once, directed so the union is on its left
+	seen := make(map[[2]Point]bool)
+	var edges [][2]Point
+	for _, e := range splitEdges(rings) {
+		a, b := e[0], e[1]
+		key := e
+		if b.X < a.X || (b.X == a.X && b.Y < a.Y) {
+			key = [2]Point{b, a}
+		}
+		if a.Equals(b) || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		mid := a.Lerp(b, 0.5)
+		n := Point{X: -(b.Y - a.Y) / length * eps, Y: (b.X - a.X) / length * eps}
+		left, right := inside(mid.Add(n)), inside(Point{X: mid.X - n.X, Y: mid.Y - n.Y})
+		if left && !right {
+			edges = append(edges, [2]Point{a, b})
+		} else if right && !left {
+			edges = append(edges, [2]Point{b, a})
+		}
+	}
+
+	var exteriors, holes [][]Point
+	for _, r := range chainEdges(edges) {
+		r = removeCollinear(r)
+		if len(r) < 3 {
+			continue
+		}
+		if Ring(r).IsClockwise() {
+			holes = append(holes, r)
+		} else {
+			exteriors = append(exteriors, r)
+		}
+	}
+
+	var fill Color
+	if len(polys) > 0 {
+		fill = polys[0].Fill
+	}
+	ret := make([]Polygon, len(exteriors))
+	for i, e := range exteriors {
+		ret[i] = Polygon{Fill: fill, Exterior: e}
+	}
+	// a hole belongs to the smallest exterior around the area to its right
+	for _, h := range holes {
+		a, b := h[0], h[1]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		q := Point{X: (a.X+b.X)/2 + (b.Y-a.Y)/length*eps, Y: (a.Y+b.Y)/2 - (b.X-a.X)/length*eps}
+		best := -1
+		for i, e := range exteriors {
+			if windingNumber(e, q) != 0 && (best < 0 || Ring(e).Area() < Ring(exteriors[best]).Area()) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return nil, fmt.Errorf("union produced a hole outside every outline")
+		}
+		ret[best].Holes = append(ret[best].Holes, h)
+	}
+
+	for i := range ret {
+		ret[i].NormalizeWinding(false)
+		if err := Triangulate(&ret[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// splitEdges returns the edges of every ring, split wherever they touch
+// another edge. Points shared by two pieces are always identical.
+func splitEdges(rings [][]Point) (ret [][2]Point) {
+	var edges [][2]Point
+	for _, r := range rings {
+		for i := range r {
+			edges = append(edges, [2]Point{r[i], Ring(r).At(i + 1)})
+		}
+	}
+	splits := make([][]Point, len(edges))
+	for i := range edges {
+		for j := i + 1; j < len(edges); j++ {
+			for _, p := range edgeIntersections(edges[i], edges[j]) {
+				splits[i] = append(splits[i], p)
+				splits[j] = append(splits[j], p)
+			}
+		}
+	}
+
+	for i, e := range edges {
+		a, b := e[0], e[1]
+		along := func(p Point) float64 {
+			return (p.X-a.X)*(b.X-a.X) + (p.Y-a.Y)*(b.Y-a.Y)
+		}
+		pts := append(splits[i], a, b)
+		sort.Slice(pts, func(i, j int) bool { return along(pts[i]) < along(pts[j]) })
+		pts = RemoveDuplicates(pts, func(p, q Point) bool { return p.Equals(q) })
+		for k := 1; k < len(pts); k++ {
+			ret = append(ret, [2]Point{pts[k-1], pts[k]})
+		}
+	}
+	return
+}
+
+// edgeIntersections lists where two edges touch, snapped to an endpoint when
+// they meet at one. Collinear edges touch at the endpoints of each that lie on
+// the other.
+func edgeIntersections(e, f [2]Point) (ret []Point) {
+	p, r := e[0], Point{X: e[1].X - e[0].X, Y: e[1].Y - e[0].Y}
+	q, s := f[0], Point{X: f[1].X - f[0].X, Y: f[1].Y - f[0].Y}
+	denom := r.X*s.Y - r.Y*s.X
+	const tol = 1e-12
+
+	if math.Abs(denom) <= tol*math.Hypot(r.X, r.Y)*math.Hypot(s.X, s.Y) {
+		// parallel, they only touch if collinear
+		if math.Abs(cross(e[0], e[1], f[0])) > tol*math.Hypot(r.X, r.Y)*math.Hypot(f[0].X-e[0].X, f[0].Y-e[0].Y) {
+			return nil
+		}
+		within := func(x, a, b Point) bool {
+			d := (x.X-a.X)*(b.X-a.X) + (x.Y-a.Y)*(b.Y-a.Y)
+			return d > 0 && d < (b.X-a.X)*(b.X-a.X)+(b.Y-a.Y)*(b.Y-a.Y)
+		}
+		for _, x := range f {
+			if within(x, e[0], e[1]) {
+				ret = append(ret, x)
+			}
+		}
+		for _, x := range e {
+			if within(x, f[0], f[1]) {
+				ret = append(ret, x)
+			}
+		}
+		return
+	}
+
+	qp := Point{X: q.X - p.X, Y: q.Y - p.Y}
+	t := (qp.X*s.Y - qp.Y*s.X) / denom
+	u := (qp.X*r.Y - qp.Y*r.X) / denom
+	if t < -tol || t > 1+tol || u < -tol || u > 1+tol {
+		return nil
+	}
+	switch {
+	case math.Abs(u) <= tol:
+		return []Point{f[0]}
+	case math.Abs(u-1) <= tol:
+		return []Point{f[1]}
+	case math.Abs(t) <= tol:
+		return []Point{e[0]}
+	case math.Abs(t-1) <= tol:
+		return []Point{e[1]}
+	}
+	return []Point{p.Lerp(e[1], t)}
+}
+
+// chainEdges joins directed edges end to start into closed rings. Where
+// several edges leave a point the sharpest left turn is taken, which keeps
+// outlines that only touch at a corner apart.
+func chainEdges(edges [][2]Point) (rings [][]Point) {
+	out := make(map[Point][]int)
+	for i, e := range edges {
+		out[e[0]] = append(out[e[0]], i)
+	}
+	used := make([]bool, len(edges))
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+		var ring []Point
+		for i := start; i >= 0 && !used[i]; {
+			used[i] = true
+			e := edges[i]
+			ring = append(ring, e[0])
+
+			next, best := -1, math.Inf(-1)
+			d := Point{X: e[1].X - e[0].X, Y: e[1].Y - e[0].Y}
+			for _, j := range out[e[1]] {
+				if used[j] && j != start {
+					continue
+				}
+				o := Point{X: edges[j][1].X - e[1].X, Y: edges[j][1].Y - e[1].Y}
+				if turn := math.Atan2(d.X*o.Y-d.Y*o.X, d.X*o.X+d.Y*o.Y); turn > best {
+					next, best = j, turn
+				}
+			}
+			i = next
+		}
+		rings = append(rings, ring)
+	}
+	return
+}
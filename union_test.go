@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnionSquares(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		svg  string
+		area float64
+		box  BoundingBox
+	}{
+		{"rectangle", `<svg><rect width="10" height="10"/><rect x="5" width="10" height="10"/></svg>`,
+			150, BoundingBox{Max: Point{X: 15, Y: 10}}},
+		{"l", `<svg><rect width="10" height="10"/><rect x="5" y="5" width="10" height="10"/></svg>`,
+			175, BoundingBox{Max: Point{X: 15, Y: 15}}},
+	} {
+		u, err := Union(convert(t, c.svg, DefaultOptions()))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if a := math.Abs(Ring(u.Exterior).Area()) / 2; math.Abs(a-c.area) > 1e-9 || len(u.Holes) != 0 {
+			t.Errorf("%s: got area %g with %d holes, want %g", c.name, a, len(u.Holes), c.area)
+		}
+		if b := u.Bounds(); !near(b, c.box) {
+			t.Errorf("%s: got bounds %v, want %v", c.name, b, c.box)
+		}
+		covered := 0.
+		for _, tri := range u.TrianglePoints() {
+			covered += math.Abs(Ring(tri[:]).Area()) / 2
+		}
+		if math.Abs(covered-c.area) > 1e-9 {
+			t.Errorf("%s: triangles cover %g, want %g", c.name, covered, c.area)
+		}
+	}
+}
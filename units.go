@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+var lengthParser = regexp.MustCompile(`^([+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][+-]?[0-9]+)?)\s*([A-Za-z%]*)$`)
+
+// millimetersPer is the size of each absolute unit, a unitless length is in
+// px which css fixes at 96 to the inch
+var millimetersPer = map[string]float64{
+	"":   25.4 / 96,
+	"px": 25.4 / 96,
+	"in": 25.4,
+	"cm": 10,
+	"mm": 1,
+	"q":  0.25,
+	"pt": 25.4 / 72,
+	"pc": 25.4 / 6,
+}
+
+// ParseLength splits an svg length like 100mm into its value and unit
+func ParseLength(s string) (float64, string, error) {
+	m := lengthParser.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, "", fmt.Errorf("invalid length '%s'", s)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return v, strings.ToLower(m[2]), nil
+}
+
+// LengthMillimeters converts a length in an absolute unit to millimeters,
+// relative units like % and em have no physical size and are an error
+func LengthMillimeters(s string) (float64, error) {
+	v, unit, err := ParseLength(s)
+	if err != nil {
+		return 0, err
+	}
+	if scale, ok := millimetersPer[unit]; ok {
+		return v * scale, nil
+	}
+	return 0, fmt.Errorf("length '%s' has no physical size", s)
+}
+
+// millimeterTransform maps the user units of the root svg onto millimeters
+// using its width, height and viewBox. A missing width or height is taken
+// to be the viewBox size in px and without a viewBox user units are px.
+func millimeterTransform(root *svgparser.Element) (Matrix, error) {
+	px := millimetersPer["px"]
+	vbAttr := strings.TrimSpace(root.Attributes["viewBox"])
+	if vbAttr == "" {
+		return Scale(px, px), nil
+	}
+	vb, err := parseViewBox(vbAttr)
+	if err != nil {
+		return Identity, err
+	}
+
+	size := func(name string, def float64) (float64, error) {
+		if root.Attributes[name] == "" {
+			return def * px, nil
+		}
+		return LengthMillimeters(root.Attributes[name])
+	}
+	w, err := size("width", vb[2])
+	if err != nil {
+		return Identity, err
+	}
+	h, err := size("height", vb[3])
+	if err != nil {
+		return Identity, err
+	}
+	return viewBoxTransform(vb, w, h), nil
+}
+
+// lengthAttr reads an attribute holding a length in user units, returning def
+// when it is absent. Absolute units are converted through px and percentages
+// are of the normalized diagonal of the current viewport.
+func (o Options) lengthAttr(el *svgparser.Element, name string, def float64) (float64, error) {
+	s := strings.TrimSpace(el.Attributes[name])
+	if s == "" {
+		return def, nil
+	}
+	v, unit, err := ParseLength(s)
+	if err != nil {
+		return 0, err
+	}
+	if unit == "%" {
+		w, h := o.viewport[0], o.viewport[1]
+		if w <= 0 || h <= 0 {
+			return 0, fmt.Errorf("%s '%s' is relative to an unknown viewport", name, s)
+		}
+		return v / 100 * math.Hypot(w, h) / math.Sqrt2, nil
+	}
+	if scale, ok := millimetersPer[unit]; ok {
+		return v * scale / millimetersPer["px"], nil
+	}
+	return 0, fmt.Errorf("%s '%s' has an unsupported unit", name, s)
+}
+
+// viewportSize is the size of the user space an svg element establishes,
+// its viewBox or else its width and height. Sizes that can't be known are
+// zero.
+func viewportSize(el *svgparser.Element) (size [2]float64, err error) {
+	if vbAttr := strings.TrimSpace(el.Attributes["viewBox"]); vbAttr != "" {
+		vb, err := parseViewBox(vbAttr)
+		if err != nil {
+			return size, err
+		}
+		return [2]float64{vb[2], vb[3]}, nil
+	}
+	for i, name := range []string{"width", "height"} {
+		if attr := el.Attributes[name]; attr != "" && !strings.HasSuffix(strings.TrimSpace(attr), "%") {
+			if size[i], err = LengthMillimeters(attr); err != nil {
+				return
+			}
+			size[i] /= millimetersPer["px"]
+		}
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMillimeters(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Millimeters = true
+	for _, c := range []struct {
+		svg  string
+		want Point
+	}{
+		{`<svg width="100mm" height="50mm" viewBox="0 0 1000 500"><rect x="500" y="100" width="500" height="400"/></svg>`, Point{X: 100, Y: 50}},
+		{`<svg width="10cm" height="5cm" viewBox="0 0 1000 500"><rect x="500" y="100" width="500" height="400"/></svg>`, Point{X: 100, Y: 50}},
+	} {
+		polys := convert(t, c.svg, opts)
+		if len(polys) != 1 {
+			t.Fatalf("got %d polygons, want 1", len(polys))
+		}
+		b := polys[0].Bounds()
+		if math.Abs(b.Min.X-50) > 1e-9 || math.Abs(b.Min.Y-10) > 1e-9 ||
+			math.Abs(b.Max.X-c.want.X) > 1e-9 || math.Abs(b.Max.Y-c.want.Y) > 1e-9 {
+			t.Errorf("rect spans %v in millimeters, want (50, 10) to %v", b, c.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/JoshVarga/svgparser"
+)
+
+// parseViewBox reads the min-x, min-y, width and height of a viewBox attribute
+func parseViewBox(s string) (vb [4]float64, err error) {
+	box := coordsSplitter.Split(strings.TrimSpace(s), -1)
+	if len(box) != 4 {
+		return vb, fmt.Errorf("invalid viewBox '%s'", s)
+	}
+	for i := range vb {
+		if vb[i], err = strconv.ParseFloat(box[i], 64); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// viewBoxTransform maps vb into a width by height viewport using the default
+// preserveAspectRatio of xMidYMid meet
+func viewBoxTransform(vb [4]float64, width, height float64) Matrix {
+	if vb[2] <= 0 || vb[3] <= 0 {
+		return Identity
+	}
+	s := math.Min(width/vb[2], height/vb[3])
+	return Translate((width-vb[2]*s)/2, (height-vb[3]*s)/2).
+		Multiply(Scale(s, s)).
+		Multiply(Translate(-vb[0], -vb[1]))
+}
+
+// viewportTransform maps the content of a nested <svg> into its parent, at
+// its x and y and with its viewBox fit to its width and height. A size that
+// is missing or a percentage is taken from the viewBox.
+func viewportTransform(el *svgparser.Element) (Matrix, error) {
+	x, err := floatAttr(el, "x", 0)
+	if err != nil {
+		return Identity, err
+	}
+	y, err := floatAttr(el, "y", 0)
+	if err != nil {
+		return Identity, err
+	}
+	m := Translate(x, y)
+
+	vbAttr := el.Attributes["viewBox"]
+	if strings.TrimSpace(vbAttr) == "" {
+		return m, nil
+	}
+	vb, err := parseViewBox(vbAttr)
+	if err != nil {
+		return Identity, err
+	}
+	size := func(name string, def float64) (float64, error) {
+		if strings.HasSuffix(strings.TrimSpace(el.Attributes[name]), "%") {
+			return def, nil
+		}
+		return floatAttr(el, name, def)
+	}
+	w, err := size("width", vb[2])
+	if err != nil {
+		return Identity, err
+	}
+	h, err := size("height", vb[3])
+	if err != nil {
+		return Identity, err
+	}
+	return m.Multiply(viewBoxTransform(vb, w, h)), nil
+}
+
+// useTransform returns the transform from the user space of a <use> element
+// into its referenced content, m being the user space of the <use> itself
+// with its transform already applied. The target's own transform goes on top
+// when the target is walked, so together they make
+// m * transform(use) * translate(x, y) * transform(target).
+func useTransform(use, target *svgparser.Element, m Matrix) (Matrix, error) {
+	x, err := floatAttr(use, "x", 0)
+	if err != nil {
+		return m, err
+	}
+	y, err := floatAttr(use, "y", 0)
+	if err != nil {
+		return m, err
+	}
+	m = m.Multiply(Translate(x, y))
+
+	vbAttr := target.Attributes["viewBox"]
+	if target.Name != "symbol" || strings.TrimSpace(vbAttr) == "" {
+		return m, nil
+	}
+	vb, err := parseViewBox(vbAttr)
+	if err != nil {
+		return m, err
+	}
+
+	// the viewport size comes from the <use>, then the symbol, then the viewBox
+	size := func(name string, def float64) (float64, error) {
+		if use.Attributes[name] != "" {
+			return floatAttr(use, name, def)
+		}
+		return floatAttr(target, name, def)
+	}
+	w, err := size("width", vb[2])
+	if err != nil {
+		return m, err
+	}
+	h, err := size("height", vb[3])
+	if err != nil {
+		return m, err
+	}
+	return m.Multiply(viewBoxTransform(vb, w, h)), nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// boundsOf returns the bounds of every polygon ordered by their min x
+func boundsOf(polys []Polygon) []BoundingBox {
+	ret := make([]BoundingBox, len(polys))
+	for i, p := range polys {
+		ret[i] = p.Bounds()
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Min.X < ret[j].Min.X })
+	return ret
+}
+
+// near reports whether every corner of the boxes is within 1e-9
+func near(a, b BoundingBox) bool {
+	return math.Abs(a.Min.X-b.Min.X) < 1e-9 && math.Abs(a.Min.Y-b.Min.Y) < 1e-9 &&
+		math.Abs(a.Max.X-b.Max.X) < 1e-9 && math.Abs(a.Max.Y-b.Max.Y) < 1e-9
+}
+
+func TestSymbolAtTwoSizes(t *testing.T) {
+	got := boundsOf(convert(t, `<svg>
+<symbol id="square" viewBox="0 0 10 10"><rect width="10" height="10"/></symbol>
+<use href="#square" width="20" height="20"/>
+<use href="#square" x="50" width="40" height="40"/>
+</svg>`, DefaultOptions()))
+	want := []BoundingBox{
+		{Min: Point{X: 0, Y: 0}, Max: Point{X: 20, Y: 20}},
+		{Min: Point{X: 50, Y: 0}, Max: Point{X: 90, Y: 40}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want only the two instances", got)
+	}
+	for i := range want {
+		if !near(got[i], want[i]) {
+			t.Errorf("instance %d spans %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNestedSVG(t *testing.T) {
+	got := boundsOf(convert(t, `<svg>
+<rect width="5" height="5"/>
+<svg x="10" y="10" width="20" height="20" viewBox="0 0 10 10"><rect width="10" height="10"/></svg>
+<svg x="40" y="10"><rect width="10" height="10"/></svg>
+</svg>`, DefaultOptions()))
+	want := []BoundingBox{
+		{Min: Point{X: 0, Y: 0}, Max: Point{X: 5, Y: 5}},
+		{Min: Point{X: 10, Y: 10}, Max: Point{X: 30, Y: 30}},
+		{Min: Point{X: 40, Y: 10}, Max: Point{X: 50, Y: 20}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !near(got[i], want[i]) {
+			t.Errorf("rect %d spans %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSelfReferentialUse(t *testing.T) {
+	doc, err := ParseDocument(strings.NewReader(`<svg>
+<g id="loop"><rect width="1" height="1"/><use href="#loop" x="2"/></g>
+</svg>`), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := ExtractPolygons(doc, DefaultOptions())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "recursively") {
+			t.Errorf("failed with %v, want the recursion reported", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expanding the use didn't stop")
+	}
+
+	// uses that fan out without a cycle are held by the depth limit
+	doc, err = ParseDocument(strings.NewReader(`<svg>
+<rect id="a" width="1" height="1"/>
+<g id="b"><use href="#a"/><use href="#a"/></g>
+<g id="c"><use href="#b"/><use href="#b"/></g>
+<g id="d"><use href="#c"/><use href="#c"/></g>
+</svg>`), DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.MaxDepth = 3
+	var limit *LimitError
+	if _, err := ExtractPolygons(doc, opts); !errors.As(err, &limit) {
+		t.Errorf("failed with %v, want the depth limit", err)
+	}
+}
+
+func TestUseAndTargetTransforms(t *testing.T) {
+	polys := convert(t, `<svg>
+<defs><rect id="r" transform="rotate(90)" width="10" height="5"/></defs>
+<use href="#r" x="100" transform="scale(2)"/>
+</svg>`, DefaultOptions())
+	if len(polys) != 1 {
+		t.Fatalf("got %d polygons, want the used rect", len(polys))
+	}
+	// the use's transform, then its x and y, then the rect's own transform
+	m, err := ParseTransform("scale(2) translate(100 0) rotate(90)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []Point
+	for _, p := range []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 5}, {X: 0, Y: 5}} {
+		want = append(want, m.Apply(p))
+	}
+	if got := polys[0].Bounds(); !near(got, Ring(want).Bounds()) {
+		t.Errorf("got bounds %v, want %v", got, Ring(want).Bounds())
+	}
+	for _, v := range polys[0].Exterior {
+		found := false
+		for _, w := range want {
+			found = found || math.Hypot(v.X-w.X, v.Y-w.Y) < 1e-9
+		}
+		if !found {
+			t.Errorf("vertex %v isn't one of the corners %v", v, want)
+		}
+	}
+}
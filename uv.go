@@ -0,0 +1,38 @@
+package main
+
+// PlanarUVs maps vertices into [0, 1] across box, u along x and v along y.
+// A box with no width or height maps that direction to 0.
+func PlanarUVs(vertices []Point, box BoundingBox) []Point {
+	w, h := box.Max.X-box.Min.X, box.Max.Y-box.Min.Y
+	ret := make([]Point, len(vertices))
+	for i, v := range vertices {
+		if w > 0 {
+			ret[i].X = (v.X - box.Min.X) / w
+		}
+		if h > 0 {
+			ret[i].Y = (v.Y - box.Min.Y) / h
+		}
+	}
+	return ret
+}
+
+// SceneBounds is the box around every vertex of polys
+func SceneBounds(polys []Polygon) BoundingBox {
+	var points []Point
+	for _, p := range polys {
+		points = append(points, p.Vertices()...)
+	}
+	return Ring(points).Bounds()
+}
+
+// uvs are the texture coordinates written for vertices of a polygon, nil
+// unless GenerateUVs is set
+func (o Options) uvs(vertices []Point) []Point {
+	if !o.GenerateUVs {
+		return nil
+	}
+	if o.UVBounds != nil {
+		return PlanarUVs(vertices, *o.UVBounds)
+	}
+	return PlanarUVs(vertices, Ring(vertices).Bounds())
+}
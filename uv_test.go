@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestUVCorners(t *testing.T) {
+	opts := DefaultOptions()
+	opts.GenerateUVs = true
+	polys := convert(t, `<svg><rect x="10" y="20" width="20" height="40"/></svg>`, opts)
+	var buf bytes.Buffer
+	writeAll(t, NewOBJWriter(&buf, opts), polys...)
+
+	vs, vts := objLines(buf.String(), "v"), objLines(buf.String(), "vt")
+	if len(vs) == 0 || len(vts) != len(vs) {
+		t.Fatalf("got %d vertices and %d uvs, want one uv each", len(vs), len(vts))
+	}
+	corners := 0
+	for i := range vs {
+		var x, y, z, u, v float64
+		fmt.Sscanf(vs[i], "v %g %g %g", &x, &y, &z)
+		fmt.Sscanf(vts[i], "vt %g %g", &u, &v)
+		switch {
+		case x == 10 && y == 20:
+			corners++
+			if u != 0 || v != 0 {
+				t.Errorf("min corner has uv (%g, %g), want (0, 0)", u, v)
+			}
+		case x == 30 && y == 60:
+			corners++
+			if u != 1 || v != 1 {
+				t.Errorf("max corner has uv (%g, %g), want (1, 1)", u, v)
+			}
+		}
+	}
+	if corners != 2 {
+		t.Errorf("found %d of the bounding box corners, want 2", corners)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// WebGLBuffers is a set of polygons flattened for upload to the gpu, three
+// floats per vertex and three indices per triangle
+type WebGLBuffers struct {
+	Positions []float32 `json:"positions"`
+	Indices   []uint32  `json:"indices"`
+}
+
+// Add appends the vertices and triangles of p at depth z
+func (b *WebGLBuffers) Add(p Polygon, z float64) {
+	b.add(p.Vertices(), p.Triangles, z)
+}
+
+func (b *WebGLBuffers) add(vertices []Point, triangles []Triangle, z float64) {
+	base := uint32(len(b.Positions) / 3)
+	for _, v := range vertices {
+		b.Positions = append(b.Positions, float32(v.X), float32(v.Y), float32(z))
+	}
+	for _, t := range triangles {
+		b.Indices = append(b.Indices, base+uint32(t[0]), base+uint32(t[1]), base+uint32(t[2]))
+	}
+}
+
+// Base64 encodes each buffer as little endian bytes in base64, ready for a
+// typed array
+func (b *WebGLBuffers) Base64() (positions, indices string) {
+	encode := func(data interface{}) string {
+		var buf bytes.Buffer
+		// writing to a bytes.Buffer can't fail
+		binary.Write(&buf, binary.LittleEndian, data)
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return encode(b.Positions), encode(b.Indices)
+}
+
+// WebGLWriter writes every polygon into one pair of buffers as a json object,
+// either as arrays or as base64 strings
+type WebGLWriter struct {
+	w      io.Writer
+	opts   Options
+	base64 bool
+	buf    WebGLBuffers
+}
+
+func NewWebGLWriter(w io.Writer, base64 bool, opts Options) *WebGLWriter {
+	return &WebGLWriter{w: w, base64: base64, opts: opts}
+}
+
+func (g *WebGLWriter) WritePolygon(p Polygon) error {
+	vertices, triangles := g.opts.mesh(p)
+	g.buf.add(vertices, triangles, g.opts.Z)
+	return nil
+}
+
+func (g *WebGLWriter) Close() error {
+	if !g.base64 {
+		return json.NewEncoder(g.w).Encode(g.buf)
+	}
+	positions, indices := g.buf.Base64()
+	return json.NewEncoder(g.w).Encode(struct {
+		Positions string `json:"positions"`
+		Indices   string `json:"indices"`
+	}{positions, indices})
+}
+
+// ColoredVertex is a vertex carrying the fill of its polygon
+type ColoredVertex struct {
+	Point
+	Color Color `json:"color"`
+}
+
+// ExpandTriangles lists three vertices for every triangle of polys, in order,
+// for renderers without index buffers
+func ExpandTriangles(polys []Polygon) (ret []ColoredVertex) {
+	for _, p := range polys {
+		vertices := p.Vertices()
+		for _, t := range p.Triangles {
+			for _, i := range t {
+				ret = append(ret, ColoredVertex{Point: vertices[i], Color: p.Fill})
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+func TestWebGLBuffersReconstruct(t *testing.T) {
+	polys := []Polygon{triangleAt(0, 0), triangleAt(10, 5)}
+	polys[1].Exterior = append(polys[1].Exterior, Point{X: 11, Y: 6})
+	polys[1].Triangles = append(polys[1].Triangles, Triangle{1, 3, 2})
+
+	var want [][3]Point
+	for _, p := range polys {
+		want = append(want, p.TrianglePoints()...)
+	}
+	check := func(form string, b WebGLBuffers) {
+		if len(b.Indices) != 3*len(want) {
+			t.Fatalf("%s buffers have %d indices, want %d", form, len(b.Indices), 3*len(want))
+		}
+		for i := range want {
+			for k := 0; k < 3; k++ {
+				v := b.Indices[3*i+k]
+				got := Point{X: float64(b.Positions[3*v]), Y: float64(b.Positions[3*v+1])}
+				if got != want[i][k] {
+					t.Errorf("%s corner %d of triangle %d is %v, want %v", form, k, i, got, want[i][k])
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writeAll(t, NewWebGLWriter(&buf, false, DefaultOptions()), polys...)
+	var arrays WebGLBuffers
+	if err := json.Unmarshal(buf.Bytes(), &arrays); err != nil {
+		t.Fatalf("decoding buffers: %v", err)
+	}
+	check("json", arrays)
+
+	buf.Reset()
+	writeAll(t, NewWebGLWriter(&buf, true, DefaultOptions()), polys...)
+	var encoded struct{ Positions, Indices string }
+	if err := json.Unmarshal(buf.Bytes(), &encoded); err != nil {
+		t.Fatalf("decoding base64 buffers: %v", err)
+	}
+	var decoded WebGLBuffers
+	for _, c := range []struct {
+		s    string
+		data interface{}
+	}{{encoded.Positions, &decoded.Positions}, {encoded.Indices, &decoded.Indices}} {
+		b, err := base64.StdEncoding.DecodeString(c.s)
+		if err != nil {
+			t.Fatalf("decoding base64: %v", err)
+		}
+		switch d := c.data.(type) {
+		case *[]float32:
+			*d = make([]float32, len(b)/4)
+		case *[]uint32:
+			*d = make([]uint32, len(b)/4)
+		}
+		if err := binary.Read(bytes.NewReader(b), binary.LittleEndian, c.data); err != nil {
+			t.Fatalf("reading buffer: %v", err)
+		}
+	}
+	check("base64", decoded)
+}
+
+func TestExpandTriangles(t *testing.T) {
+	polys := convert(t, `<svg>
+<rect width="10" height="10" fill="#ff0000"/>
+<path d="M20 0 L30 0 L30 10 L25 5 L20 10 Z" fill="#0000ff"/>
+</svg>`, DefaultOptions())
+	total := 0
+	for _, p := range polys {
+		total += len(p.Triangles)
+	}
+	vertices := ExpandTriangles(polys)
+	if len(vertices) != 3*total {
+		t.Fatalf("got %d vertices, want %d for %d triangles", len(vertices), 3*total, total)
+	}
+	i := 0
+	for _, p := range polys {
+		for _, tri := range p.TrianglePoints() {
+			for _, corner := range tri {
+				if v := vertices[i]; v.Point != corner || v.Color != p.Fill {
+					t.Errorf("vertex %d is %v, want %v in %v", i, v, corner, p.Fill)
+				}
+				i++
+			}
+		}
+	}
+}
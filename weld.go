@@ -0,0 +1,73 @@
+package main
+
+// Weld returns the vertices used by the triangles of p with every coincident
+// pair merged, and the triangles reindexed against them. Vertices are in the
+// order the triangles first use them, ones no triangle uses are dropped.
+func (p Polygon) Weld() (vertices []Point, triangles []Triangle) {
+	all := p.Vertices()
+	index := make(map[Point]int)
+	triangles = make([]Triangle, 0, len(p.Triangles))
+	for _, t := range p.Triangles {
+		var w Triangle
+		for k, i := range t {
+			j, ok := index[all[i]]
+			if !ok {
+				j = len(vertices)
+				index[all[i]] = j
+				vertices = append(vertices, all[i])
+			}
+			w[k] = j
+		}
+		triangles = append(triangles, w)
+	}
+	return
+}
+
+// mesh is the vertices and triangles the indexed writers output for p,
+// welded and double sided when the options ask for it
+func (o Options) mesh(p Polygon) ([]Point, []Triangle) {
+	if o.Weld {
+		vertices, triangles := p.Weld()
+		return vertices, o.doubleSided(triangles)
+	}
+	return p.Vertices(), o.doubleSided(p.Triangles)
+}
+
+// doubleSided follows triangles with each of them again in the opposite
+// winding when DoubleSided is set, facing the other way
+func (o Options) doubleSided(triangles []Triangle) []Triangle {
+	if !o.DoubleSided {
+		return triangles
+	}
+	ret := make([]Triangle, 0, 2*len(triangles))
+	ret = append(ret, triangles...)
+	for _, t := range triangles {
+		ret = append(ret, Triangle{t[0], t[2], t[1]})
+	}
+	return ret
+}
+
+// meshColors lines up the PerVertexColors of p with the vertices mesh
+// returned for it, nil when p has none
+func (o Options) meshColors(p Polygon, vertices []Point) []Color {
+	if p.PerVertexColors == nil {
+		return nil
+	}
+	var colors []Color
+	for _, c := range p.PerVertexColors {
+		colors = append(colors, c...)
+	}
+	if !o.Weld {
+		return colors
+	}
+	// welded vertices are at the same place so have the same color
+	at := make(map[Point]Color)
+	for i, v := range p.Vertices() {
+		at[v] = colors[i]
+	}
+	ret := make([]Color, len(vertices))
+	for i, v := range vertices {
+		ret[i] = at[v]
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWeld(t *testing.T) {
+	// a hole touching the exterior repeats the corner at (0, 0)
+	p := Polygon{
+		Exterior:  []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 0, Y: 10}},
+		Holes:     [][]Point{{{X: 0, Y: 0}, {X: 1, Y: 2}, {X: 2, Y: 1}}},
+		Triangles: []Triangle{{0, 1, 4}, {3, 5, 2}},
+	}
+	vertices, triangles := p.Weld()
+	if len(vertices) != 5 {
+		t.Errorf("got vertices %v, want the repeated corner welded into one of 5", vertices)
+	}
+	want := p.TrianglePoints()
+	for i, tri := range triangles {
+		for k, v := range tri {
+			if vertices[v] != want[i][k] {
+				t.Errorf("corner %d of triangle %d is %v, want %v", k, i, vertices[v], want[i][k])
+			}
+		}
+	}
+	if triangles[0][0] != triangles[1][0] {
+		t.Errorf("triangles %v don't share the welded corner", triangles)
+	}
+}
+
+func TestDoubleSided(t *testing.T) {
+	polys := convert(t, `<svg><rect width="10" height="10"/><path d="M20 0 L30 0 L25 10 Z" fill="#000000"/></svg>`, DefaultOptions())
+	opts := DefaultOptions()
+	opts.DoubleSided = true
+	for _, p := range polys {
+		vertices, triangles := opts.mesh(p)
+		n := len(p.Triangles)
+		if len(triangles) != 2*n {
+			t.Errorf("got %d triangles, want twice the %d", len(triangles), n)
+			continue
+		}
+		for i, tri := range triangles[:n] {
+			back := triangles[n+i]
+			front := Ring{vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]}.Area()
+			if b := (Ring{vertices[back[0]], vertices[back[1]], vertices[back[2]]}).Area(); b != -front || front == 0 {
+				t.Errorf("triangle %v has area %g and its back %v %g, want them mirrored", tri, front, back, b)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writeAll(t, NewOBJWriter(&buf, opts), triangleAt(0, 0))
+	if f := objLines(buf.String(), "f"); strings.Join(f, ",") != "f 1 2 3,f 1 3 2" {
+		t.Errorf("got faces %q, want the triangle and its reverse", f)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MeshWriter receives polygons one at a time as they are converted, Close
+// must be called to finish the output
+type MeshWriter interface {
+	WritePolygon(p Polygon) error
+	Close() error
+}
+
+func NewMeshWriter(format string, w io.Writer, opts Options) (MeshWriter, error) {
+	switch format {
+	case "json":
+		return NewJSONWriter(w, opts), nil
+	case "obj":
+		return NewOBJWriter(w, opts), nil
+	case "stl":
+		return NewSTLWriter(w, false, opts), nil
+	case "stlb":
+		return NewSTLWriter(w, true, opts), nil
+	case "ply":
+		return NewPLYWriter(w, opts), nil
+	case "gltf":
+		return NewGLTFWriter(w, opts), nil
+	case "webgl":
+		return NewWebGLWriter(w, false, opts), nil
+	case "webgl64":
+		return NewWebGLWriter(w, true, opts), nil
+	case "bin":
+		return NewBinaryWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown output format '%s'", format)
+}
+
+// triangleNormal is the z direction of the face, +1 for counter-clockwise
+func triangleNormal(a, b, c Point) float64 {
+	if (b.X-a.X)*(c.Y-a.Y)-(b.Y-a.Y)*(c.X-a.X) < 0 {
+		return -1
+	}
+	return 1
+}
+
+// obj numbers vertices from 1, triangles are offset by this when written
+const objIndexBase = 1
+
+type OBJWriter struct {
+	w    io.Writer
+	opts Options
+	// index the next vertex written will have in the file
+	next int
+
+	// polygons written so far, unnamed polygons are numbered by it
+	count int
+
+	// when set every polygon is an object using a material for its fill,
+	// materials are written to mtl the first time their fill is seen
+	mtl       io.Writer
+	mtllib    string
+	materials map[string]bool
+}
+
+// NewOBJWriter writes each polygon as an object named after its id, or
+// polygon_n for the nth polygon without one
+func NewOBJWriter(w io.Writer, opts Options) *OBJWriter {
+	return &OBJWriter{w: w, opts: opts, next: objIndexBase}
+}
+
+// NewOBJMaterialWriter writes each polygon as an object named fill_rrggbb
+// after its fill, using a material of the same name. The materials go to mtl,
+// which the obj references as mtllib.
+func NewOBJMaterialWriter(w, mtl io.Writer, mtllib string, opts Options) *OBJWriter {
+	return &OBJWriter{w: w, opts: opts, next: objIndexBase, mtl: mtl, mtllib: mtllib, materials: make(map[string]bool)}
+}
+
+// material names the object and material of p, writing the material the
+// first time it is used
+func (o *OBJWriter) material(p Polygon) error {
+	if o.next == objIndexBase && len(o.materials) == 0 {
+		if _, err := fmt.Fprintf(o.w, "mtllib %s\n", o.mtllib); err != nil {
+			return err
+		}
+	}
+	name := "fill_" + strings.TrimPrefix(p.Fill.Hex(), "#")
+	if !o.materials[name] {
+		o.materials[name] = true
+		if _, err := fmt.Fprintf(o.mtl, "newmtl %s\nKd %f %f %f\nd %f\n\n", name, p.Fill.R, p.Fill.G, p.Fill.B, p.Fill.A); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(o.w, "o %s\nusemtl %s\n", name, name)
+	return err
+}
+
+// objectName is the id of p, or its position among the polygons written when
+// it has none, with whitespace that would end the name replaced
+func (o *OBJWriter) objectName(p Polygon) string {
+	if p.ID == "" {
+		return fmt.Sprintf("polygon_%d", o.count)
+	}
+	return strings.Join(strings.Fields(p.ID), "_")
+}
+
+func (o *OBJWriter) WritePolygon(p Polygon) error {
+	if o.mtl != nil {
+		if err := o.material(p); err != nil {
+			return err
+		}
+	} else if _, err := fmt.Fprintf(o.w, "o %s\n", o.objectName(p)); err != nil {
+		return err
+	}
+	o.count++
+	vertices, triangles := o.opts.mesh(p)
+	d := o.opts.decimals()
+	for _, v := range vertices {
+		if _, err := fmt.Fprintf(o.w, "v %.*f %.*f %.*f\n", d, v.X, d, v.Y, d, o.opts.Z); err != nil {
+			return err
+		}
+	}
+	uvs := o.opts.uvs(vertices)
+	for _, uv := range uvs {
+		if _, err := fmt.Fprintf(o.w, "vt %.*f %.*f\n", d, uv.X, d, uv.Y); err != nil {
+			return err
+		}
+	}
+	for _, t := range triangles {
+		t = t.Offset(o.next)
+		a, b, c := t[0], t[1], t[2]
+		var err error
+		// texture coordinates are numbered the same as the vertices
+		if uvs != nil {
+			_, err = fmt.Fprintf(o.w, "f %d/%d %d/%d %d/%d\n", a, a, b, b, c, c)
+		} else {
+			_, err = fmt.Fprintf(o.w, "f %d %d %d\n", a, b, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	o.next += len(vertices)
+	return nil
+}
+
+func (o *OBJWriter) Close() error {
+	return nil
+}
+
+// STLWriter writes ascii or binary STL. Binary STL needs the triangle count up
+// front so it is patched in on Close when w can seek, otherwise the triangles
+// are buffered until Close.
+type STLWriter struct {
+	w      io.Writer
+	opts   Options
+	binary bool
+	count  uint32
+	// set when binary output can be patched in place, origin is where the
+	// header starts
+	seeker io.WriteSeeker
+	origin int64
+	// binary output is held here when w can't seek
+	buf     bytes.Buffer
+	started bool
+}
+
+func NewSTLWriter(w io.Writer, binary bool, opts Options) *STLWriter {
+	s := &STLWriter{w: w, opts: opts, binary: binary}
+	if ws, ok := w.(io.WriteSeeker); ok && binary {
+		// pipes implement Seek but fail when it is called
+		if origin, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			s.seeker, s.origin = ws, origin
+		}
+	}
+	return s
+}
+
+func (s *STLWriter) out() io.Writer {
+	if s.binary && s.seeker == nil {
+		return &s.buf
+	}
+	return s.w
+}
+
+func (s *STLWriter) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	if !s.binary {
+		_, err := fmt.Fprintf(s.w, "solid itsfive\n")
+		return err
+	} else if s.seeker == nil {
+		return nil
+	}
+	// header and a placeholder triangle count
+	_, err := s.w.Write(make([]byte, 84))
+	return err
+}
+
+func (s *STLWriter) WritePolygon(p Polygon) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	w := s.out()
+	z, d := s.opts.Z, s.opts.decimals()
+	vertices := p.Vertices()
+	for _, t := range s.opts.doubleSided(p.Triangles) {
+		a, b, c := vertices[t[0]], vertices[t[1]], vertices[t[2]]
+		n := triangleNormal(a, b, c)
+		if !s.binary {
+			if _, err := fmt.Fprintf(w, "facet normal 0 0 %g\nouter loop\nvertex %.*f %.*f %.*f\nvertex %.*f %.*f %.*f\nvertex %.*f %.*f %.*f\nendloop\nendfacet\n",
+				n, d, a.X, d, a.Y, d, z, d, b.X, d, b.Y, d, z, d, c.X, d, c.Y, d, z); err != nil {
+				return err
+			}
+		} else {
+			facet := [12]float32{0, 0, float32(n),
+				float32(a.X), float32(a.Y), float32(z),
+				float32(b.X), float32(b.Y), float32(z),
+				float32(c.X), float32(c.Y), float32(z),
+			}
+			if err := binary.Write(w, binary.LittleEndian, facet); err != nil {
+				return err
+			} else if err := binary.Write(w, binary.LittleEndian, uint16(0)); err != nil {
+				return err
+			}
+		}
+		s.count++
+	}
+	return nil
+}
+
+func (s *STLWriter) Close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if !s.binary {
+		_, err := fmt.Fprintf(s.w, "endsolid itsfive\n")
+		return err
+	}
+
+	if s.seeker != nil {
+		end, err := s.seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		} else if _, err := s.seeker.Seek(s.origin+80, io.SeekStart); err != nil {
+			return err
+		} else if err := binary.Write(s.seeker, binary.LittleEndian, s.count); err != nil {
+			return err
+		}
+		_, err = s.seeker.Seek(end, io.SeekStart)
+		return err
+	}
+
+	if _, err := s.w.Write(make([]byte, 80)); err != nil {
+		return err
+	} else if err := binary.Write(s.w, binary.LittleEndian, s.count); err != nil {
+		return err
+	}
+	_, err := s.buf.WriteTo(s.w)
+	return err
+}
+
+// PLYWriter writes ascii PLY. The header carries the vertex and face counts
+// so the body is buffered until Close. When any polygon has PerVertexColors
+// every vertex gets a color, the fill of its polygon if it has none.
+type PLYWriter struct {
+	w         io.Writer
+	opts      Options
+	vertices  []Point
+	colors    []Color
+	colored   bool
+	faces     bytes.Buffer
+	faceCount int
+}
+
+func NewPLYWriter(w io.Writer, opts Options) *PLYWriter {
+	return &PLYWriter{w: w, opts: opts}
+}
+
+func (p *PLYWriter) WritePolygon(poly Polygon) error {
+	vertices, triangles := p.opts.mesh(poly)
+	colors := p.opts.meshColors(poly, vertices)
+	if colors == nil {
+		colors = make([]Color, len(vertices))
+		for i := range colors {
+			colors[i] = poly.Fill
+		}
+	} else {
+		p.colored = true
+	}
+	for _, t := range triangles {
+		// ply numbers vertices from 0
+		t = t.Offset(len(p.vertices))
+		fmt.Fprintf(&p.faces, "3 %d %d %d\n", t[0], t[1], t[2])
+	}
+	p.vertices = append(p.vertices, vertices...)
+	p.colors = append(p.colors, colors...)
+	p.faceCount += len(triangles)
+	return nil
+}
+
+func (p *PLYWriter) Close() error {
+	w := bufio.NewWriter(p.w)
+	fmt.Fprintf(w, "ply\nformat ascii 1.0\nelement vertex %d\nproperty float x\nproperty float y\nproperty float z\n", len(p.vertices))
+	if p.colored {
+		fmt.Fprintf(w, "property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	}
+	fmt.Fprintf(w, "element face %d\nproperty list uchar int vertex_indices\nend_header\n", p.faceCount)
+
+	d := p.opts.decimals()
+	for i, v := range p.vertices {
+		fmt.Fprintf(w, "%.*f %.*f %.*f", d, v.X, d, v.Y, d, p.opts.Z)
+		if p.colored {
+			c := p.colors[i]
+			fmt.Fprintf(w, " %d %d %d %d", colorByte(c.R), colorByte(c.G), colorByte(c.B), colorByte(c.A))
+		}
+		w.WriteString("\n")
+	}
+	if _, err := p.faces.WriteTo(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// JSONWriter writes the same array that encoding a []Polygon would, one
+// element at a time
+type JSONWriter struct {
+	w     io.Writer
+	opts  Options
+	count int
+}
+
+func NewJSONWriter(w io.Writer, opts Options) *JSONWriter {
+	return &JSONWriter{w: w, opts: opts}
+}
+
+func (j *JSONWriter) WritePolygon(p Polygon) error {
+	b, err := json.Marshal(jsonPolygon(p, j.opts))
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if j.count == 0 {
+		sep = "["
+	}
+	j.count++
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *JSONWriter) Close() error {
+	end := "]\n"
+	if j.count == 0 {
+		// what encoding a nil slice of no polygons gives
+		end = "null\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
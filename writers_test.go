@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// triangleAt is a one triangle polygon with its corner at x, y
+func triangleAt(x, y float64) Polygon {
+	return Polygon{
+		Exterior:  []Point{{X: x, Y: y}, {X: x + 1, Y: y}, {X: x, Y: y + 1}},
+		Triangles: []Triangle{{0, 1, 2}},
+		Fill:      Color{A: 1},
+	}
+}
+
+// writeAll streams polys through w and closes it
+func writeAll(t *testing.T, w MeshWriter, polys ...Polygon) {
+	t.Helper()
+	for _, p := range polys {
+		if err := w.WritePolygon(p); err != nil {
+			t.Fatalf("writing polygon: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing writer: %v", err)
+	}
+}
+
+// objLines returns the lines of an obj file starting with the keyword
+func objLines(obj, keyword string) (lines []string) {
+	for _, l := range strings.Split(obj, "\n") {
+		if strings.HasPrefix(l, keyword+" ") {
+			lines = append(lines, l)
+		}
+	}
+	return
+}
+
+func TestOBJWriterStreams(t *testing.T) {
+	var buf bytes.Buffer
+	writeAll(t, NewOBJWriter(&buf, DefaultOptions()), triangleAt(0, 0), triangleAt(10, 0), triangleAt(20, 0))
+	obj := buf.String()
+
+	if o := objLines(obj, "o"); len(o) != 3 {
+		t.Errorf("got objects %q, want 3", o)
+	}
+	if v := objLines(obj, "v"); len(v) != 9 {
+		t.Errorf("got %d vertices, want 9", len(v))
+	}
+	// each object's faces use its own vertices
+	want := []string{"f 1 2 3", "f 4 5 6", "f 7 8 9"}
+	if f := objLines(obj, "f"); strings.Join(f, ",") != strings.Join(want, ",") {
+		t.Errorf("got faces %q, want %q", f, want)
+	}
+}
+
+func TestJSONWriterMatchesEncoder(t *testing.T) {
+	for _, svg := range []string{
+		`<svg><rect width="10" height="10" fill="#ff0000"/><path d="M0 0 L10 0 L10 10 Z M2 2 L8 2 L8 8 Z" fill="#00ff0080"/></svg>`,
+		`<svg></svg>`,
+	} {
+		for _, floats := range []bool{false, true} {
+			opts := DefaultOptions()
+			opts.FloatColors = floats
+			polys := convert(t, svg, opts)
+
+			var batch, streamed bytes.Buffer
+			if err := json.NewEncoder(&batch).Encode(Map(polys, func(p Polygon) interface{} { return jsonPolygon(p, opts) })); err != nil {
+				t.Fatalf("encoding polygons: %v", err)
+			}
+			w := NewJSONWriter(&streamed, opts)
+			for _, p := range polys {
+				if err := w.WritePolygon(p); err != nil {
+					t.Fatalf("writing polygon: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("closing writer: %v", err)
+			}
+			if !bytes.Equal(batch.Bytes(), streamed.Bytes()) {
+				t.Errorf("%d polygons streamed as\n%s\nencoded as\n%s", len(polys), streamed.String(), batch.String())
+			}
+		}
+	}
+}
+
+func TestWritersZ(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Z = 2.5
+	// vertex lines of each format and the field holding z
+	for _, c := range []struct {
+		format  string
+		keyword string
+		field   int
+	}{
+		{"obj", "v", 3},
+		{"stl", "vertex", 3},
+		{"ply", "", 2},
+	} {
+		var buf bytes.Buffer
+		w, err := NewMeshWriter(c.format, &buf, opts)
+		if err != nil {
+			t.Fatalf("making %s writer: %v", c.format, err)
+		}
+		writeAll(t, w, triangleAt(0, 0), triangleAt(10, 0))
+
+		out := buf.String()
+		if c.format == "ply" {
+			out = out[strings.Index(out, "end_header\n")+len("end_header\n"):]
+		}
+		vertices := 0
+		for _, l := range strings.Split(out, "\n") {
+			fields := strings.Fields(l)
+			if c.keyword != "" {
+				if len(fields) == 0 || fields[0] != c.keyword {
+					continue
+				}
+			} else if len(fields) != 3 {
+				// ply faces have a count and three indices
+				continue
+			}
+			vertices++
+			if z, err := strconv.ParseFloat(fields[c.field], 64); err != nil || z != opts.Z {
+				t.Errorf("%s vertex '%s' has z %s, want %g", c.format, l, fields[c.field], opts.Z)
+			}
+		}
+		if vertices != 6 {
+			t.Errorf("%s has %d vertices, want 6", c.format, vertices)
+		}
+	}
+}
+
+func TestOBJMaterialWriter(t *testing.T) {
+	red, blue := triangleAt(0, 0), triangleAt(10, 0)
+	red.Fill, blue.Fill = Color{R: 1, A: 1}, Color{B: 1, A: 1}
+	again := triangleAt(20, 0)
+	again.Fill = red.Fill
+
+	var obj, mtl bytes.Buffer
+	writeAll(t, NewOBJMaterialWriter(&obj, &mtl, "shapes.mtl", DefaultOptions()), red, blue, again)
+
+	want := []string{"o fill_ff0000", "o fill_0000ff", "o fill_ff0000"}
+	if o := objLines(obj.String(), "o"); strings.Join(o, ",") != strings.Join(want, ",") {
+		t.Errorf("got objects %q, want %q", o, want)
+	}
+	if u := objLines(obj.String(), "usemtl"); len(u) != 3 || u[2] != "usemtl fill_ff0000" {
+		t.Errorf("got materials used %q, want one per object", u)
+	}
+	if l := objLines(obj.String(), "mtllib"); len(l) != 1 || l[0] != "mtllib shapes.mtl" {
+		t.Errorf("got %q, want the library referenced once", l)
+	}
+	if m := objLines(mtl.String(), "newmtl"); len(m) != 2 {
+		t.Errorf("got materials %q, want one per distinct fill", m)
+	}
+}
+
+func TestTriangleIndexBase(t *testing.T) {
+	p := triangleAt(0, 0)
+	var js, obj bytes.Buffer
+	writeAll(t, NewJSONWriter(&js, DefaultOptions()), p)
+	writeAll(t, NewOBJWriter(&obj, DefaultOptions()), p)
+
+	var decoded []struct {
+		Triangles [][3]int `json:"triangle"`
+	}
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("decoding json: %v", err)
+	}
+	if len(decoded) != 1 || len(decoded[0].Triangles) != 1 || decoded[0].Triangles[0] != [3]int{0, 1, 2} {
+		t.Errorf("json has triangles %v, want [[0 1 2]]", decoded)
+	}
+	if f := objLines(obj.String(), "f"); len(f) != 1 || f[0] != "f 1 2 3" {
+		t.Errorf("obj has faces %q, want f 1 2 3", f)
+	}
+	if p.Triangles[0] != (Triangle{0, 1, 2}) {
+		t.Errorf("writing changed the triangles to %v", p.Triangles)
+	}
+}
+
+func TestOBJObjects(t *testing.T) {
+	polys := convert(t, `<svg>
+<rect id="north field" width="5" height="5"/>
+<rect x="10" width="5" height="5"/>
+<path id="pond" d="M20 0 L25 0 L25 5 Z" fill="#0000ff"/>
+</svg>`, DefaultOptions())
+	var buf bytes.Buffer
+	writeAll(t, NewOBJWriter(&buf, DefaultOptions()), polys...)
+	names := map[string]bool{}
+	for _, o := range objLines(buf.String(), "o") {
+		names[strings.TrimPrefix(o, "o ")] = true
+	}
+	if len(names) != len(polys) || !names["north_field"] || !names["pond"] {
+		t.Errorf("got objects %v, want one for each of the %d polygons", names, len(polys))
+	}
+}